@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Ilmyrat1822/subs/internal/config"
+	"github.com/Ilmyrat1822/subs/internal/database"
+	"github.com/Ilmyrat1822/subs/migrations"
+)
+
+const migrateUsage = `usage: subs migrate <command>
+
+commands:
+  up             apply all pending migrations
+  down [N]       roll back the last N migrations (default 1)
+  status         list migrations and whether they are applied
+  force VERSION  mark migrations up to VERSION as applied without running them`
+
+// RunMigrate executes a migration command given the arguments that follow
+// "migrate" on the command line.
+func RunMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	cfg := config.GetConfig()
+	if err := EnsureDBExists(cfg.PostgresUri); err != nil {
+		return fmt.Errorf("failed to ensure database exists: %w", err)
+	}
+	db, err := database.Connect(cfg.PostgresUri)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	ctx := context.Background()
+	migrator := database.NewMigrator(db, migrations.FS)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d migration(s) applied", applied)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d migration(s) reverted", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", ""
+			if st.Applied {
+				state = "applied"
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.ChecksumMismatch {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		return w.Flush()
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force requires a version\n\n%s", migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		log.Printf("Schema version forced to %d", version)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+
+	return nil
+}
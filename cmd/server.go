@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
 	"strings"
 
+	"github.com/Ilmyrat1822/subs/internal/apperr"
 	"github.com/Ilmyrat1822/subs/internal/config"
 	"github.com/Ilmyrat1822/subs/internal/database"
+	"github.com/Ilmyrat1822/subs/internal/jobs"
+	"github.com/Ilmyrat1822/subs/migrations"
 	"github.com/Ilmyrat1822/subs/utils/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -19,6 +23,7 @@ type Server struct {
 	Echo     *echo.Echo
 	Config   *config.Schema
 	Database *gorm.DB
+	Jobs     *jobs.Scheduler
 }
 
 func NewServer() *Server {
@@ -35,8 +40,18 @@ func NewServer() *Server {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
+	if !cfg.DisableAutoMigration {
+		applied, err := database.NewMigrator(db, migrations.FS).Up(context.Background())
+		if err != nil {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+		log.Printf("Migrations up to date, %d applied", applied)
+	}
+
 	e := echo.New()
 	e.Validator = validator.NewValidator()
+	e.HTTPErrorHandler = apperr.HTTPErrorHandler
+	e.Use(middleware.RequestID())
 	e.Use(middleware.RequestLogger())
 	e.Use(middleware.Recover())
 
@@ -44,6 +59,7 @@ func NewServer() *Server {
 		Echo:     e,
 		Config:   cfg,
 		Database: db,
+		Jobs:     jobs.NewScheduler(),
 	}
 }
 
@@ -0,0 +1,153 @@
+// Package apperr defines the typed errors shared by the modules and renders
+// them as RFC 7807 problem details.
+package apperr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind classifies an error by how a client should react to it. Every kind
+// maps to one HTTP status.
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	NotFound
+	Conflict
+	PreconditionFailed
+	PreconditionRequired
+	TooLarge
+	UnsupportedMediaType
+	Unprocessable
+	FailedDependency
+	NotAcceptable
+)
+
+// Error is a domain error. Code is stable and names the problem type, so
+// clients can rely on it while Message is meant for people.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+
+	// localize renders the error in one of the languages a client accepts.
+	localize func(languages []string) *Error
+}
+
+// FieldError describes what is wrong with one field of a request, or with
+// one line of an uploaded file.
+type FieldError struct {
+	Field   string `json:"field,omitempty" example:"end_date"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message" example:"end_date must not be before start_date"`
+}
+
+// New creates an error, usually assigned to a package-level sentinel.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// BadRequest creates an error for a malformed request.
+func BadRequest(message string) *Error {
+	return New(Invalid, "invalid-request", message)
+}
+
+// Validation creates the error for a request whose fields failed validation.
+// summary introduces the messages of the fields in the error text.
+func Validation(summary string, fields ...FieldError) *Error {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Message
+	}
+	return &Error{
+		Kind:    Invalid,
+		Code:    "validation-failed",
+		Message: summary + ": " + strings.Join(parts, "; "),
+		Fields:  fields,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports errors with the same code as equal, so errors made by
+// WithField still match their sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithField returns a copy of e that blames field for the error. message
+// continues the field name, as in "must not be before start_date".
+func (e *Error) WithField(field, message string) *Error {
+	message = field + " " + message
+	fields := append(append([]FieldError(nil), e.Fields...), FieldError{Field: field, Message: message})
+	return &Error{
+		Kind:    e.Kind,
+		Code:    e.Code,
+		Message: e.Message + ": " + message,
+		Fields:  fields,
+	}
+}
+
+// Localized sets how e is rendered in other languages and returns e.
+// localize is given the languages a client accepts, most preferred first.
+func (e *Error) Localized(localize func(languages []string) *Error) *Error {
+	e.localize = localize
+	return e
+}
+
+// In returns e rendered in the first of languages it supports, or e itself.
+func (e *Error) In(languages []string) *Error {
+	if e.localize == nil || len(languages) == 0 {
+		return e
+	}
+	return e.localize(languages)
+}
+
+// fieldErrors is implemented by errors that list several problems, such as
+// a rejected file upload.
+type fieldErrors interface {
+	FieldErrors() []FieldError
+}
+
+// FieldsOf collects the field errors of every error in the chain of err,
+// in the first of languages they are available in.
+func FieldsOf(err error, languages []string) []FieldError {
+	var fields []FieldError
+	walk(err, func(err error) {
+		switch e := err.(type) {
+		case *Error:
+			fields = append(fields, e.In(languages).Fields...)
+		case fieldErrors:
+			fields = append(fields, e.FieldErrors()...)
+		}
+	})
+	return fields
+}
+
+func walk(err error, visit func(error)) {
+	if err == nil {
+		return
+	}
+	visit(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		walk(e.Unwrap(), visit)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			walk(inner, visit)
+		}
+	}
+}
+
+// As returns the first Error in the chain of err.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,161 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProblemContentType is the media type of problem responses.
+const ProblemContentType = "application/problem+json"
+
+// typePrefix makes problem type URIs from error codes. The URIs are relative
+// references, resolved against the API's own address.
+const typePrefix = "/problems/"
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type      string       `json:"type" example:"/problems/subscription-not-found"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"subscription not found"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/subs/6f1c1c8e-0a53-4c09-9a35-7f9b4b3c3b1d"`
+	RequestID string       `json:"request_id,omitempty" example:"Sg4xT1f0mDf0l3kPz5d2q8R1vJb7wYcN"`
+	Errors    []FieldError `json:"errors,omitempty"`
+} // @name Problem
+
+var kindStatus = map[Kind]int{
+	Internal:             http.StatusInternalServerError,
+	Invalid:              http.StatusBadRequest,
+	NotFound:             http.StatusNotFound,
+	Conflict:             http.StatusConflict,
+	PreconditionFailed:   http.StatusPreconditionFailed,
+	PreconditionRequired: http.StatusPreconditionRequired,
+	TooLarge:             http.StatusRequestEntityTooLarge,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	Unprocessable:        http.StatusUnprocessableEntity,
+	FailedDependency:     http.StatusFailedDependency,
+	NotAcceptable:        http.StatusNotAcceptable,
+}
+
+// statusCodes names the problem types of errors raised by Echo itself, such
+// as unknown routes or malformed bodies.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "invalid-request",
+	http.StatusNotFound:              "not-found",
+	http.StatusMethodNotAllowed:      "method-not-allowed",
+	http.StatusRequestEntityTooLarge: "too-large",
+	http.StatusUnsupportedMediaType:  "unsupported-media-type",
+	http.StatusTooManyRequests:       "too-many-requests",
+}
+
+// Status returns the HTTP status for err: the status of its kind for an
+// Error, the code of an echo.HTTPError, and 500 for anything else.
+func Status(err error) int {
+	if e, ok := As(err); ok {
+		return kindStatus[e.Kind]
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// ProblemOf describes err as a problem, localized to the first of languages
+// that is supported. The text of unexpected errors is not included, so
+// database and driver messages do not reach clients.
+func ProblemOf(err error, languages []string) Problem {
+	status := Status(err)
+	p := Problem{Type: "about:blank", Title: http.StatusText(status), Status: status}
+
+	if e, ok := As(err); ok {
+		p.Type = typePrefix + e.Code
+		p.Detail = err.Error()
+		if err == error(e) {
+			p.Detail = e.In(languages).Message
+		}
+		p.Errors = FieldsOf(err, languages)
+		return p
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		if code, ok := statusCodes[status]; ok {
+			p.Type = typePrefix + code
+		}
+		if detail := fmt.Sprint(he.Message); status < http.StatusInternalServerError && detail != p.Title {
+			p.Detail = detail
+		}
+		return p
+	}
+
+	p.Type = typePrefix + "internal"
+	return p
+}
+
+// HTTPErrorHandler is installed as the Echo error handler. It answers every
+// error with application/problem+json carrying the request ID, in the
+// language of the Accept-Language header where possible, and logs the errors
+// that are hidden from the client.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	p := ProblemOf(err, AcceptedLanguages(c.Request().Header.Get("Accept-Language")))
+	p.Instance = c.Request().URL.Path
+	p.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	if p.Status >= http.StatusInternalServerError {
+		log.Printf("Request %s %s %s failed: %v", p.RequestID, c.Request().Method, p.Instance, err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.Status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, ProblemContentType)
+		err = c.JSON(p.Status, p)
+	}
+	if err != nil {
+		log.Printf("Failed to send error response: %v", err)
+	}
+}
+
+// AcceptedLanguages lists the locales of an Accept-Language header, most
+// preferred first, in the form the locales package names them ("ru_RU").
+// Every regional locale is followed by its base language.
+func AcceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" && tag != "*" && q > 0 {
+			tags = append(tags, weighted{tag: strings.ReplaceAll(tag, "-", "_"), q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	var languages []string
+	for _, t := range tags {
+		languages = append(languages, t.tag)
+		if base, _, regional := strings.Cut(t.tag, "_"); regional {
+			languages = append(languages, base)
+		}
+	}
+	return languages
+}
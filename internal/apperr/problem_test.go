@@ -0,0 +1,210 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// errNotFound is available in English, the default, and Russian.
+var errNotFound = New(NotFound, "subscription-not-found", "subscription not found").
+	Localized(func(languages []string) *Error {
+		for _, language := range languages {
+			if language == "en" {
+				break
+			}
+			if language == "ru" {
+				return New(NotFound, "subscription-not-found", "подписка не найдена")
+			}
+		}
+		return New(NotFound, "subscription-not-found", "subscription not found")
+	})
+
+func TestProblemOf(t *testing.T) {
+	invalidEnd := BadRequest("invalid dates").WithField("end_date", "must not be before start_date")
+
+	tests := []struct {
+		name      string
+		err       error
+		languages []string
+		want      Problem
+	}{
+		{
+			name: "error",
+			err:  errNotFound,
+			want: Problem{Type: "/problems/subscription-not-found", Title: "Not Found", Status: 404, Detail: "subscription not found"},
+		},
+		{
+			name:      "localized error",
+			err:       errNotFound,
+			languages: []string{"ru"},
+			want:      Problem{Type: "/problems/subscription-not-found", Title: "Not Found", Status: 404, Detail: "подписка не найдена"},
+		},
+		{
+			name: "wrapped error keeps its context",
+			err:  fmt.Errorf("restore 2f1c3f1e: %w", errNotFound),
+			want: Problem{Type: "/problems/subscription-not-found", Title: "Not Found", Status: 404, Detail: "restore 2f1c3f1e: subscription not found"},
+		},
+		{
+			name: "field errors",
+			err:  fmt.Errorf("operation 2: %w", invalidEnd),
+			want: Problem{
+				Type:   "/problems/invalid-request",
+				Title:  "Bad Request",
+				Status: 400,
+				Detail: "operation 2: invalid dates: end_date must not be before start_date",
+				Errors: []FieldError{{Field: "end_date", Message: "end_date must not be before start_date"}},
+			},
+		},
+		{
+			name: "field errors of every joined error",
+			err:  fmt.Errorf("%w: %w", Validation("validation failed", FieldError{Field: "price", Message: "price is required"}), invalidEnd),
+			want: Problem{
+				Type:   "/problems/validation-failed",
+				Title:  "Bad Request",
+				Status: 400,
+				Detail: "validation failed: price is required: invalid dates: end_date must not be before start_date",
+				Errors: []FieldError{
+					{Field: "price", Message: "price is required"},
+					{Field: "end_date", Message: "end_date must not be before start_date"},
+				},
+			},
+		},
+		{
+			name: "internal error is hidden",
+			err:  fmt.Errorf("list subscriptions: %w", errors.New(`pq: relation "subscriptions" does not exist`)),
+			want: Problem{Type: "/problems/internal", Title: "Internal Server Error", Status: 500},
+		},
+		{
+			name: "echo client error",
+			err:  echo.NewHTTPError(http.StatusMethodNotAllowed, "method not allowed on this route"),
+			want: Problem{Type: "/problems/method-not-allowed", Title: "Method Not Allowed", Status: 405, Detail: "method not allowed on this route"},
+		},
+		{
+			name: "echo error without detail",
+			err:  echo.ErrNotFound,
+			want: Problem{Type: "/problems/not-found", Title: "Not Found", Status: 404},
+		},
+		{
+			name: "echo server error is hidden",
+			err:  echo.NewHTTPError(http.StatusServiceUnavailable, "dial tcp 10.0.0.5:5432: connection refused"),
+			want: Problem{Type: "about:blank", Title: "Service Unavailable", Status: 503},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProblemOf(tt.err, tt.languages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProblemOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// handle renders err for a request the way the server does, with the
+// request ID middleware having set the response header.
+func handle(err error, method string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/subs/2f1c3f1e", nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+	HTTPErrorHandler(err, c)
+	return rec
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "error",
+			err:  errNotFound,
+			want: Problem{
+				Type: "/problems/subscription-not-found", Title: "Not Found", Status: 404, Detail: "subscription not found",
+				Instance: "/api/v1/subs/2f1c3f1e", RequestID: "req-1",
+			},
+		},
+		{
+			name: "internal error",
+			err:  errors.New("pq: deadlock detected"),
+			want: Problem{
+				Type: "/problems/internal", Title: "Internal Server Error", Status: 500,
+				Instance: "/api/v1/subs/2f1c3f1e", RequestID: "req-1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := handle(tt.err, http.MethodGet, nil)
+			if rec.Code != tt.want.Status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want.Status)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+			}
+			var got Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body is not a problem: %v\n%s", err, rec.Body)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problem = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerLocalizes(t *testing.T) {
+	rec := handle(errNotFound, http.MethodGet, map[string]string{"Accept-Language": "de-DE, ru-RU;q=0.8, en;q=0.5"})
+	var got Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Detail != "подписка не найдена" {
+		t.Errorf("detail = %q, want the Russian text", got.Detail)
+	}
+}
+
+func TestHTTPErrorHandlerHead(t *testing.T) {
+	rec := handle(errNotFound, http.MethodHead, nil)
+	if rec.Code != http.StatusNotFound || rec.Body.Len() != 0 {
+		t.Errorf("HEAD = %d with %d bytes, want 404 without a body", rec.Code, rec.Body.Len())
+	}
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: nil},
+		{header: "ru", want: []string{"ru"}},
+		{header: "ru-RU", want: []string{"ru_RU", "ru"}},
+		{header: "en;q=0.5, ru-RU, *;q=0.1", want: []string{"ru_RU", "ru", "en"}},
+		{header: "de;q=0, en", want: []string{"en"}},
+		{header: "fr;q=abc, en;q=0.9", want: []string{"fr", "en"}},
+	}
+
+	for _, tt := range tests {
+		if got := AcceptedLanguages(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AcceptedLanguages(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
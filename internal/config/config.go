@@ -4,28 +4,41 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/caarlos0/env"
 	"github.com/joho/godotenv"
 )
 
 type Schema struct {
-	Port                 string `env:"PORT"`
-	PostgresUri          string `env:"POSTGRES_URI"`
-	DisableAutoMigration bool   `env:"DISABLE_AUTO_MIGRATION" envDefault:"true"`
+	Port                     string        `env:"PORT"`
+	PostgresUri              string        `env:"POSTGRES_URI"`
+	DisableAutoMigration     bool          `env:"DISABLE_AUTO_MIGRATION" envDefault:"false"`
+	DefaultCurrency          string        `env:"DEFAULT_CURRENCY" envDefault:"RUB"`
+	TrialConversionInterval  time.Duration `env:"TRIAL_CONVERSION_INTERVAL" envDefault:"1h"`
+	TrashRetention           time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	TrashPurgeInterval       time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+	RequireIfMatch           bool          `env:"REQUIRE_IF_MATCH" envDefault:"false"`
+	IdempotencyKeyTTL        time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	IdempotencyCleanup       time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL" envDefault:"1h"`
+	BulkMaxOperations        int           `env:"BULK_MAX_OPERATIONS" envDefault:"100"`
+	LegacyAPIDeprecatedAt    string        `env:"LEGACY_API_DEPRECATED_AT" envDefault:"2026-10-19"`
+	LegacyAPISunset          string        `env:"LEGACY_API_SUNSET" envDefault:"2027-04-30"`
+	BudgetEvaluationInterval time.Duration `env:"BUDGET_EVALUATION_INTERVAL" envDefault:"1h"`
+	DuplicateGuard           string        `env:"DUPLICATE_GUARD" envDefault:"off"`
 }
 
 var cfg Schema
 
+// GetConfig reads the configuration from the environment. Without PORT set
+// the variables are loaded from .env first. Unset variables take the
+// envDefault of their field.
 func GetConfig() *Schema {
-	cfg.Port = os.Getenv("PORT")
-	cfg.PostgresUri = os.Getenv("POSTGRES_URI")
-	cfg.DisableAutoMigration = os.Getenv("DISABLE_AUTO_MIGRATION") == "true"
-	if cfg.Port == "" {
+	if os.Getenv("PORT") == "" {
 		_ = godotenv.Load(filepath.Join(".env"))
-		if err := env.Parse(&cfg); err != nil {
-			log.Fatalf("Error on parsing configuration file, error: %v", err)
-		}
+	}
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("Error on parsing configuration file, error: %v", err)
 	}
 	return &cfg
 }
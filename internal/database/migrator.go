@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey is the pg_advisory_lock key held while migrations run, so
+// replicas starting at the same time apply them one after another.
+const migrationLockKey int64 = 7_340_112_004
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied          bool
+	AppliedAt        *time.Time
+	ChecksumMismatch bool
+}
+
+type Migrator struct {
+	db     *gorm.DB
+	source fs.FS
+}
+
+func NewMigrator(db *gorm.DB, source fs.FS) *Migrator {
+	return &Migrator{db: db, source: source}
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied. It refuses to run when an applied migration was edited.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		statuses, err := m.status(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+		todo, err := pending(statuses)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range todo {
+			if err := m.apply(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			log.Printf("Applied migration %d_%s", mig.Version, mig.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the n most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("number of migrations to roll back must be positive")
+	}
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		statuses, err := m.status(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(statuses) - 1; i >= 0 && reverted < n; i-- {
+			st := statuses[i]
+			if !st.Applied {
+				continue
+			}
+			if st.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", st.Version, st.Name)
+			}
+			if err := m.apply(ctx, conn, st.Migration, st.Down, false); err != nil {
+				return err
+			}
+			log.Printf("Reverted migration %d_%s", st.Version, st.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		var err error
+		statuses, err = m.status(ctx, conn, migrations)
+		return err
+	})
+	return statuses, err
+}
+
+// Force records the schema as being exactly at version without running any
+// SQL: migrations up to and including version are marked applied with their
+// current checksums, later ones are marked pending. Passing 0 clears the
+// table. It is meant for recovering from a failed or hand-applied migration.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		known := version == 0
+		for _, mig := range migrations {
+			if mig.Version == version {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown migration version %d", version)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum,
+			); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock. Advisory locks belong to a session, so everything has to go through
+// the same connection rather than the pool.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, migrations []Migration) error) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn, migrations)
+}
+
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn, migrations []Migration) ([]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedRow)
+	for rows.Next() {
+		var version int64
+		var row appliedRow
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return compareApplied(migrations, applied), nil
+}
+
+// appliedRow is a row of schema_migrations.
+type appliedRow struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// compareApplied reports for each of migrations, in version order, whether
+// it is among the applied rows and whether its checksum still matches.
+func compareApplied(migrations []Migration, applied map[int64]appliedRow) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int64]bool, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		st := MigrationStatus{Migration: mig}
+		if row, ok := applied[mig.Version]; ok {
+			appliedAt := row.appliedAt
+			st.Applied = true
+			st.AppliedAt = &appliedAt
+			st.ChecksumMismatch = row.checksum != mig.Checksum
+		}
+		statuses = append(statuses, st)
+	}
+	// Versions applied by a newer binary are tolerated so that a rollback of
+	// the service does not stop it from starting.
+	for version := range applied {
+		if !known[version] {
+			log.Printf("Migration %d is applied but unknown to this binary", version)
+		}
+	}
+	return statuses
+}
+
+// pending returns the migrations of statuses that still have to be applied,
+// in version order. It fails when an applied migration was edited.
+func pending(statuses []MigrationStatus) ([]Migration, error) {
+	var todo []Migration
+	for _, st := range statuses {
+		if st.ChecksumMismatch {
+			return nil, fmt.Errorf("migration %d_%s was modified after it was applied", st.Version, st.Name)
+		}
+		if !st.Applied {
+			todo = append(todo, st.Migration)
+		}
+	}
+	return todo, nil
+}
+
+// apply runs a migration script and records the result in the same
+// transaction, so a failing script leaves schema_migrations untouched.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, script string, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		direction := "down"
+		if up {
+			direction = "up"
+		}
+		return fmt.Errorf("migration %d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			mig.Version, mig.Name, mig.Checksum,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.source, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(m.source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, mig.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
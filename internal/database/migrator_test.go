@@ -0,0 +1,164 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int64
+		wantErr      string
+	}{
+		{
+			name: "ordered by version, not by name",
+			files: fstest.MapFS{
+				"000010_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (c);")},
+				"000002_create_t.up.sql":    {Data: []byte("CREATE TABLE t (c INT);")},
+				"000002_create_t.down.sql":  {Data: []byte("DROP TABLE t;")},
+				"000001_init.up.sql":        {Data: []byte("SELECT 1;")},
+				"embed.go":                  {Data: []byte("package migrations")},
+				"000003_ignored_dir.up.sql": {Mode: fs.ModeDir | 0o755},
+			},
+			wantVersions: []int64{1, 2, 10},
+		},
+		{
+			name: "version used twice",
+			files: fstest.MapFS{
+				"000014_add_categories.up.sql":                   {Data: []byte("SELECT 1;")},
+				"000014_add_idempotency_response_headers.up.sql": {Data: []byte("SELECT 2;")},
+			},
+			wantErr: "migration version 14 is used by both",
+		},
+		{
+			name: "down without up",
+			files: fstest.MapFS{
+				"000001_init.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			wantErr: "has no up file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := NewMigrator(nil, tt.files).load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			var versions []int64
+			for _, mig := range migrations {
+				versions = append(versions, mig.Version)
+				if mig.Checksum != checksum(mig.Up) {
+					t.Errorf("migration %d checksum = %s, want the SHA-256 of its up file", mig.Version, mig.Checksum)
+				}
+			}
+			if !reflect.DeepEqual(versions, tt.wantVersions) {
+				t.Errorf("load() versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "init", Up: "SELECT 1;", Checksum: checksum("SELECT 1;")},
+		{Version: 2, Name: "create_t", Up: "SELECT 2;", Checksum: checksum("SELECT 2;")},
+		{Version: 3, Name: "add_index", Up: "SELECT 3;", Checksum: checksum("SELECT 3;")},
+	}
+	at := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		applied map[int64]appliedRow
+		want    []int64
+		wantErr string
+	}{
+		{
+			name: "fresh database",
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "already applied are skipped",
+			applied: map[int64]appliedRow{
+				1: {checksum: checksum("SELECT 1;"), appliedAt: at},
+				2: {checksum: checksum("SELECT 2;"), appliedAt: at},
+			},
+			want: []int64{3},
+		},
+		{
+			name: "a gap is filled in version order",
+			applied: map[int64]appliedRow{
+				1: {checksum: checksum("SELECT 1;"), appliedAt: at},
+				3: {checksum: checksum("SELECT 3;"), appliedAt: at},
+			},
+			want: []int64{2},
+		},
+		{
+			name: "up to date, with a version from a newer binary",
+			applied: map[int64]appliedRow{
+				1: {checksum: checksum("SELECT 1;"), appliedAt: at},
+				2: {checksum: checksum("SELECT 2;"), appliedAt: at},
+				3: {checksum: checksum("SELECT 3;"), appliedAt: at},
+				4: {checksum: checksum("SELECT 4;"), appliedAt: at},
+			},
+			want: nil,
+		},
+		{
+			name: "checksum mismatch",
+			applied: map[int64]appliedRow{
+				1: {checksum: checksum("SELECT 1;"), appliedAt: at},
+				2: {checksum: checksum("SELECT 'edited';"), appliedAt: at},
+			},
+			wantErr: "migration 2_create_t was modified after it was applied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := compareApplied(migrations, tt.applied)
+			for _, st := range statuses {
+				row, ok := tt.applied[st.Version]
+				if st.Applied != ok || (ok && !st.AppliedAt.Equal(row.appliedAt)) {
+					t.Errorf("migration %d applied = %v at %v, want %v", st.Version, st.Applied, st.AppliedAt, ok)
+				}
+			}
+
+			todo, err := pending(statuses)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("pending() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pending() error = %v", err)
+			}
+			var versions []int64
+			for _, mig := range todo {
+				versions = append(versions, mig.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("pending() = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package deprecation marks endpoints that are going away, so clients can
+// notice before they stop working.
+package deprecation
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+)
+
+// Middleware adds to every response of the routes it wraps a Deprecation
+// header (RFC 9745) with deprecatedAt, a Sunset header (RFC 8594) with the
+// time the routes will be removed, and a successor-version link to the same
+// path with prefix replaced by successor, such as /api/subs/list to
+// /api/v1/subs/list. Error responses carry the headers too. A zero sunset
+// leaves the Sunset header out, for routes without a removal date.
+func Middleware(deprecatedAt, sunset time.Time, prefix, successor string) echo.MiddlewareFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	var sunsetDate string
+	if !sunset.IsZero() {
+		sunsetDate = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, deprecation)
+			if sunsetDate != "" {
+				header.Set(HeaderSunset, sunsetDate)
+			}
+			if rest, ok := strings.CutPrefix(c.Request().URL.Path, prefix); ok {
+				header.Add("Link", "<"+successor+rest+`>; rel="successor-version"`)
+			}
+			return next(c)
+		}
+	}
+}
+
+// Window is when the unversioned routes of earlier releases were deprecated
+// and when they will be removed. Every module uses the same one.
+type Window struct {
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+// Middleware is Middleware with the dates of w.
+func (w Window) Middleware(prefix, successor string) echo.MiddlewareFunc {
+	return Middleware(w.DeprecatedAt, w.Sunset, prefix, successor)
+}
+
+// WithoutSunset is w without a removal date, for routes that stay until
+// their replacement exists.
+func (w Window) WithoutSunset() Window {
+	return Window{DeprecatedAt: w.DeprecatedAt}
+}
@@ -0,0 +1,110 @@
+package deprecation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	window := Window{
+		DeprecatedAt: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2026, time.January, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60)),
+	}
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	failing := func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound) }
+
+	tests := []struct {
+		name       string
+		window     Window
+		path       string
+		handler    echo.HandlerFunc
+		wantStatus int
+		wantSunset string
+		wantLink   string
+	}{
+		{
+			name:       "with sunset",
+			window:     window,
+			path:       "/api/subs/list",
+			handler:    ok,
+			wantStatus: http.StatusOK,
+			wantSunset: "Thu, 01 Jan 2026 09:00:00 GMT",
+			wantLink:   `</api/v1/subs/list>; rel="successor-version"`,
+		},
+		{
+			name:       "without sunset",
+			window:     window.WithoutSunset(),
+			path:       "/api/subs/2f1c3f1e/history",
+			handler:    ok,
+			wantStatus: http.StatusOK,
+			wantLink:   `</api/v1/subs/2f1c3f1e/history>; rel="successor-version"`,
+		},
+		{
+			name:       "error responses",
+			window:     window,
+			path:       "/api/subs",
+			handler:    failing,
+			wantStatus: http.StatusNotFound,
+			wantSunset: "Thu, 01 Jan 2026 09:00:00 GMT",
+			wantLink:   `</api/v1/subs>; rel="successor-version"`,
+		},
+		{
+			name:       "path outside the prefix",
+			window:     window,
+			path:       "/health",
+			handler:    ok,
+			wantStatus: http.StatusOK,
+			wantSunset: "Thu, 01 Jan 2026 09:00:00 GMT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(tt.window.Middleware("/api/subs", "/api/v1/subs"))
+			e.Any("/*", tt.handler)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(HeaderDeprecation); got != "@1751328000" {
+				t.Errorf("Deprecation = %q, want @1751328000", got)
+			}
+			if got := rec.Header().Get(HeaderSunset); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+			if got := rec.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeepsOtherLinks(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(time.Unix(0, 0), time.Time{}, "/api/subs", "/api/v1/subs"))
+	e.POST("/api/subs", func(c echo.Context) error {
+		c.Response().Header().Add("Link", `</api/v1/subs/5d2e>; rel="duplicate"`)
+		return c.NoContent(http.StatusCreated)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/subs", nil))
+
+	want := []string{`</api/v1/subs>; rel="successor-version"`, `</api/v1/subs/5d2e>; rel="duplicate"`}
+	got := rec.Header().Values("Link")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get(HeaderDeprecation); got != "@0" {
+		t.Errorf("Deprecation = %q, want @0", got)
+	}
+}
@@ -0,0 +1,159 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored with a response and sent
+// again on replay, besides Content-Type.
+var replayedHeaders = []string{"ETag", "Location", "Warning", "Link"}
+
+var (
+	ErrKeyTooLong  = apperr.New(apperr.Invalid, "idempotency-key-too-long", "Idempotency-Key must be at most 255 characters")
+	ErrKeyReused   = apperr.New(apperr.Unprocessable, "idempotency-key-reused", "Idempotency-Key was already used for a different request")
+	ErrKeyInFlight = apperr.New(apperr.Conflict, "idempotency-key-in-flight", "a request with this Idempotency-Key is still being processed")
+)
+
+// Keys keeps the idempotency keys and the responses stored for them. It is
+// implemented by Store.
+type Keys interface {
+	Reserve(key, method, path, requestHash string) (existing *models.IdempotencyKey, reserved bool, err error)
+	Complete(key string, statusCode int, contentType string, header http.Header, body []byte) error
+	Release(key string) error
+}
+
+// Middleware makes a write endpoint idempotent for requests carrying an
+// Idempotency-Key header. The first request with a key runs normally and its
+// response is stored; a retry with the same key and the same method, path
+// and body gets the stored response back without running the handler. The
+// same key with a different request is rejected with 422, and a retry that
+// arrives while the first request is still running with 409. Server errors
+// are not stored, so such requests can be retried.
+func Middleware(store Keys) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return ErrKeyTooLong
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return apperr.BadRequest("failed to read request body")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			req := c.Request()
+			hash := requestHash(req.Method, req.URL.Path, body)
+
+			stored, reserved, err := store.Reserve(key, req.Method, req.URL.Path, hash)
+			if err != nil {
+				return err
+			}
+			if !reserved {
+				switch {
+				case stored.RequestHash != hash:
+					return ErrKeyReused
+				case stored.StatusCode == 0:
+					return ErrKeyInFlight
+				}
+				header, err := Headers(stored)
+				if err != nil {
+					return err
+				}
+				replayHeaders(c.Response().Header(), header)
+				c.Response().Header().Set(HeaderReplayed, "true")
+				return c.Blob(stored.StatusCode, stored.ContentType, stored.ResponseBody)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			defer func() {
+				if r := recover(); r != nil {
+					store.Release(key)
+					panic(r)
+				}
+			}()
+
+			handlerErr := next(c)
+			if handlerErr != nil {
+				// Let Echo render the error now so that its response is recorded.
+				c.Error(handlerErr)
+			}
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				err = store.Release(key)
+			} else {
+				header := c.Response().Header()
+				err = store.Complete(key, status, header.Get(echo.HeaderContentType), storedHeaders(header), recorder.body.Bytes())
+			}
+			if err != nil {
+				log.Printf("Failed to store response for Idempotency-Key %q: %v", key, err)
+			}
+			return nil
+		}
+	}
+}
+
+// storedHeaders picks the replayed headers out of header.
+func storedHeaders(header http.Header) http.Header {
+	stored := make(http.Header)
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			stored[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return stored
+}
+
+// replayHeaders adds the stored headers to header, skipping values that are
+// already set, such as the Link added by middleware on every response.
+func replayHeaders(header, stored http.Header) {
+	for _, name := range replayedHeaders {
+		for _, value := range stored.Values(name) {
+			if !slices.Contains(header.Values(name), value) {
+				header.Add(name, value)
+			}
+		}
+	}
+}
+
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies everything written to the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
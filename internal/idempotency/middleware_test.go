@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// memoryKeys keeps keys in memory the way Store keeps them in the table.
+type memoryKeys struct {
+	mu      sync.Mutex
+	entries map[string]models.IdempotencyKey
+}
+
+func newMemoryKeys() *memoryKeys {
+	return &memoryKeys{entries: make(map[string]models.IdempotencyKey)}
+}
+
+func (k *memoryKeys) Reserve(key, method, path, requestHash string) (*models.IdempotencyKey, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if stored, ok := k.entries[key]; ok {
+		return &stored, false, nil
+	}
+	k.entries[key] = models.IdempotencyKey{Key: key, Method: method, Path: path, RequestHash: requestHash}
+	return nil, true, nil
+}
+
+func (k *memoryKeys) Complete(key string, statusCode int, contentType string, header http.Header, body []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	entry := k.entries[key]
+	entry.StatusCode, entry.ContentType, entry.ResponseHeaders, entry.ResponseBody = statusCode, contentType, headers, body
+	k.entries[key] = entry
+	return nil
+}
+
+func (k *memoryKeys) Release(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.entries, key)
+	return nil
+}
+
+// send runs a POST with key and body through the middleware in front of
+// handler, rendering errors like the server does.
+func send(e *echo.Echo, keys Keys, handler echo.HandlerFunc, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subs", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if err := Middleware(keys)(handler)(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func newEcho() *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = apperr.HTTPErrorHandler
+	return e
+}
+
+// creating answers like the create endpoint and counts its calls.
+type creating struct {
+	calls int
+}
+
+func (h *creating) handle(c echo.Context) error {
+	h.calls++
+	c.Response().Header().Set("ETag", `"1"`)
+	c.Response().Header().Set("Location", "/api/v1/subs/2f1c3f1e-8c5b-4d6e-9f7a-0b1c2d3e4f50")
+	c.Response().Header().Add("Link", `</api/v1/subs/5d2e>; rel="duplicate"`)
+	c.Response().Header().Set("X-Not-Stored", "yes")
+	return c.JSON(http.StatusCreated, map[string]int{"call": h.calls})
+}
+
+func TestMiddlewareReplays(t *testing.T) {
+	e, keys, handler := newEcho(), newMemoryKeys(), &creating{}
+	first := send(e, keys, handler.handle, "key-1", `{"service_name": "Netflix"}`)
+	replay := send(e, keys, handler.handle, "key-1", `{"service_name": "Netflix"}`)
+
+	if handler.calls != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.calls)
+	}
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", replay.Code, replay.Body, first.Code, first.Body)
+	}
+	for _, name := range []string{"ETag", "Location", "Link", echo.HeaderContentType} {
+		if got, want := replay.Header().Values(name), first.Header().Values(name); strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Errorf("replayed %s = %q, want %q", name, got, want)
+		}
+	}
+	if got := replay.Header().Get(HeaderReplayed); got != "true" {
+		t.Errorf("%s = %q, want true", HeaderReplayed, got)
+	}
+	if first.Header().Get(HeaderReplayed) != "" || replay.Header().Get("X-Not-Stored") != "" {
+		t.Errorf("first response marked replayed or unlisted header replayed: %v, %v", first.Header(), replay.Header())
+	}
+
+	// A different key is a different request.
+	send(e, keys, handler.handle, "key-2", `{"service_name": "Netflix"}`)
+	if handler.calls != 2 {
+		t.Errorf("handler called %d times for a new key, want 2", handler.calls)
+	}
+}
+
+func TestMiddlewareRejectsReusedKey(t *testing.T) {
+	e, keys, handler := newEcho(), newMemoryKeys(), &creating{}
+	send(e, keys, handler.handle, "key-1", `{"service_name": "Netflix"}`)
+	rec := send(e, keys, handler.handle, "key-1", `{"service_name": "Kion"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "idempotency-key-reused") {
+		t.Errorf("reused key = %d %s, want 422 idempotency-key-reused", rec.Code, rec.Body)
+	}
+	if handler.calls != 1 {
+		t.Errorf("handler called %d times, want 1", handler.calls)
+	}
+}
+
+func TestMiddlewareRejectsKeyInFlight(t *testing.T) {
+	e, keys := newEcho(), newMemoryKeys()
+	entered, release := make(chan struct{}), make(chan struct{})
+	slow := func(c echo.Context) error {
+		close(entered)
+		<-release
+		return c.NoContent(http.StatusCreated)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send(e, keys, slow, "key-1", `{}`) }()
+	<-entered
+
+	rec := send(e, keys, slow, "key-1", `{}`)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "idempotency-key-in-flight") {
+		t.Errorf("retry in flight = %d %s, want 409 idempotency-key-in-flight", rec.Code, rec.Body)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("first request = %d, want 201", first.Code)
+	}
+}
+
+func TestMiddlewareForgetsServerErrors(t *testing.T) {
+	e, keys := newEcho(), newMemoryKeys()
+	calls := 0
+	flaky := func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("connection reset")
+		}
+		return c.NoContent(http.StatusCreated)
+	}
+
+	if rec := send(e, keys, flaky, "key-1", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("first request = %d, want 500", rec.Code)
+	}
+	if rec := send(e, keys, flaky, "key-1", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("retry = %d replayed %q, want a fresh 201", rec.Code, rec.Header().Get(HeaderReplayed))
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
+
+func TestMiddlewarePassesRequestsWithoutKey(t *testing.T) {
+	e, keys, handler := newEcho(), newMemoryKeys(), &creating{}
+	send(e, keys, handler.handle, "", `{}`)
+	send(e, keys, handler.handle, "", `{}`)
+	if handler.calls != 2 || len(keys.entries) != 0 {
+		t.Errorf("handler called %d times with %d keys stored, want 2 and none", handler.calls, len(keys.entries))
+	}
+
+	rec := send(e, keys, handler.handle, strings.Repeat("k", maxKeyLength+1), `{}`)
+	if rec.Code != http.StatusBadRequest || handler.calls != 2 {
+		t.Errorf("too long key = %d after %d calls, want 400 without calling the handler", rec.Code, handler.calls)
+	}
+}
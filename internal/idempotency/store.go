@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// Store persists idempotency keys in the idempotency_keys table.
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Reserve claims key for a new request. When the key is already taken it
+// returns the stored entry instead and reserved is false.
+func (s *Store) Reserve(key, method, path, requestHash string) (existing *models.IdempotencyKey, reserved bool, err error) {
+	// GORM writes times in UTC into columns without a time zone, so
+	// comparisons have to be in UTC too.
+	now := time.Now().UTC()
+	entry := &models.IdempotencyKey{
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, true, nil
+	}
+
+	var stored models.IdempotencyKey
+	err = s.db.First(&stored, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The previous holder released the key in the meantime.
+		return s.Reserve(key, method, path, requestHash)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if stored.ExpiresAt.Before(now) {
+		if err := s.Release(key); err != nil {
+			return nil, false, err
+		}
+		return s.Reserve(key, method, path, requestHash)
+	}
+	return &stored, false, nil
+}
+
+// Complete stores the response to the request holding key.
+func (s *Store) Complete(key string, statusCode int, contentType string, header http.Header, body []byte) error {
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return s.db.Model(&models.IdempotencyKey{}).
+		Where("key = ?", key).
+		Updates(map[string]any{
+			"status_code":      statusCode,
+			"content_type":     contentType,
+			"response_headers": headers,
+			"response_body":    body,
+		}).Error
+}
+
+// Headers returns the response headers stored for entry.
+func Headers(entry *models.IdempotencyKey) (http.Header, error) {
+	var header http.Header
+	if len(entry.ResponseHeaders) == 0 {
+		return header, nil
+	}
+	err := json.Unmarshal(entry.ResponseHeaders, &header)
+	return header, err
+}
+
+// Release forgets key so that the request can be retried.
+func (s *Store) Release(key string) error {
+	return s.db.Delete(&models.IdempotencyKey{}, "key = ?", key).Error
+}
+
+// DeleteExpired removes the keys past their expiry and returns how many
+// there were.
+func (s *Store) DeleteExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now().UTC()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// BillingPeriod says how often a subscription's price is charged. Besides the
+// named periods it accepts "every-N-months" for arbitrary month intervals.
+type BillingPeriod string
+
+const (
+	BillingWeekly    BillingPeriod = "weekly"
+	BillingMonthly   BillingPeriod = "monthly"
+	BillingQuarterly BillingPeriod = "quarterly"
+	BillingYearly    BillingPeriod = "yearly"
+)
+
+const weeksPerYear = 52
+
+var (
+	ErrInvalidBillingPeriod = errors.New("invalid billing period, expected weekly, monthly, quarterly, yearly or every-N-months")
+
+	everyNMonthsRe = regexp.MustCompile(`^every-([1-9][0-9]*)-months$`)
+)
+
+// ParseBillingPeriod validates value and returns its canonical form. An empty
+// value means monthly, and month intervals that have a name are normalized to
+// it, so "every-3-months" becomes "quarterly".
+func ParseBillingPeriod(value string) (BillingPeriod, error) {
+	switch p := BillingPeriod(value); p {
+	case "":
+		return BillingMonthly, nil
+	case BillingWeekly, BillingMonthly, BillingQuarterly, BillingYearly:
+		return p, nil
+	}
+
+	match := everyNMonthsRe.FindStringSubmatch(value)
+	if match == nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBillingPeriod, value)
+	}
+	months, err := strconv.Atoi(match[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBillingPeriod, value)
+	}
+	return EveryNMonths(months), nil
+}
+
+// EveryNMonths returns the billing period charging once every n months.
+func EveryNMonths(n int) BillingPeriod {
+	switch n {
+	case 1:
+		return BillingMonthly
+	case 3:
+		return BillingQuarterly
+	case 12:
+		return BillingYearly
+	default:
+		return BillingPeriod(fmt.Sprintf("every-%d-months", n))
+	}
+}
+
+func (p BillingPeriod) IsWeekly() bool { return p == BillingWeekly }
+
+// IntervalMonths returns the number of months between charges, or 0 for
+// weekly billing. Unknown values are treated as monthly.
+func (p BillingPeriod) IntervalMonths() int {
+	switch p {
+	case BillingWeekly:
+		return 0
+	case BillingQuarterly:
+		return 3
+	case BillingYearly:
+		return 12
+	}
+	if match := everyNMonthsRe.FindStringSubmatch(string(p)); match != nil {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			return n
+		}
+	}
+	return 1
+}
+
+// MonthlyEquivalent spreads price over one month of this period, rounded to
+// whole minor units.
+func (p BillingPeriod) MonthlyEquivalent(price int64) int64 {
+	var monthly float64
+	if p.IsWeekly() {
+		monthly = float64(price) * weeksPerYear / 12
+	} else {
+		monthly = float64(price) / float64(p.IntervalMonths())
+	}
+	return int64(math.Round(monthly))
+}
+
+func (p *BillingPeriod) UnmarshalText(text []byte) error {
+	parsed, err := ParseBillingPeriod(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBillingPeriod(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    BillingPeriod
+		wantErr bool
+	}{
+		{value: "", want: BillingMonthly},
+		{value: "weekly", want: BillingWeekly},
+		{value: "monthly", want: BillingMonthly},
+		{value: "quarterly", want: BillingQuarterly},
+		{value: "yearly", want: BillingYearly},
+		{value: "every-1-months", want: BillingMonthly},
+		{value: "every-3-months", want: BillingQuarterly},
+		{value: "every-12-months", want: BillingYearly},
+		{value: "every-6-months", want: "every-6-months"},
+		{value: "every-0-months", wantErr: true},
+		{value: "every-06-months", wantErr: true},
+		{value: "daily", wantErr: true},
+		{value: "Monthly", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBillingPeriod(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidBillingPeriod) {
+					t.Fatalf("ParseBillingPeriod(%q) error = %v, want ErrInvalidBillingPeriod", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBillingPeriod(%q) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBillingPeriod(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingPeriodIntervalMonths(t *testing.T) {
+	tests := []struct {
+		period BillingPeriod
+		want   int
+	}{
+		{period: BillingWeekly, want: 0},
+		{period: BillingMonthly, want: 1},
+		{period: BillingQuarterly, want: 3},
+		{period: BillingYearly, want: 12},
+		{period: "every-6-months", want: 6},
+		{period: "unknown", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			if got := tt.period.IntervalMonths(); got != tt.want {
+				t.Errorf("IntervalMonths() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingPeriodMonthlyEquivalent(t *testing.T) {
+	tests := []struct {
+		period BillingPeriod
+		price  int64
+		want   int64
+	}{
+		{period: BillingMonthly, price: 39900, want: 39900},
+		{period: BillingQuarterly, price: 30000, want: 10000},
+		{period: BillingQuarterly, price: 10000, want: 3333},
+		{period: BillingYearly, price: 120000, want: 10000},
+		{period: BillingYearly, price: 99900, want: 8325},
+		{period: BillingWeekly, price: 1200, want: 5200},
+		{period: "every-6-months", price: 60000, want: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			if got := tt.period.MonthlyEquivalent(tt.price); got != tt.want {
+				t.Errorf("MonthlyEquivalent(%d) = %d, want %d", tt.price, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BudgetPeriod is the span a budget's limit applies to.
+type BudgetPeriod string
+
+const (
+	BudgetMonthly BudgetPeriod = "monthly"
+	BudgetYearly  BudgetPeriod = "yearly"
+)
+
+// DefaultBudgetThresholds are the percentages of the limit that are reported
+// when a budget does not name its own.
+var DefaultBudgetThresholds = Thresholds{80, 100}
+
+// Budget caps the spend of a user per calendar month or year, in Currency.
+// With ServiceName or Category set only subscriptions to that service or in
+// that category count against it.
+type Budget struct {
+	ID          uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID      uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Period      BudgetPeriod `gorm:"type:varchar(16);not null" swaggertype:"string" example:"monthly"`
+	Limit       int64        `gorm:"column:limit_amount;not null;check:limit_amount > 0" example:"150000"`
+	Currency    Currency     `gorm:"type:char(3);not null;default:RUB" swaggertype:"string" example:"RUB"`
+	ServiceName *string      `gorm:"type:varchar(255)" example:"Yandex Plus"`
+	Category    *string      `gorm:"type:varchar(64)" example:"streaming"`
+	Thresholds  Thresholds   `gorm:"type:integer[];not null" swaggertype:"array,integer" example:"80,100"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// PeriodAround returns the first and last month of the budget period that
+// contains month.
+func (b Budget) PeriodAround(month YearMonth) (YearMonth, YearMonth) {
+	if b.Period == BudgetYearly {
+		return NewYearMonth(month.Year(), time.January), NewYearMonth(month.Year(), time.December)
+	}
+	return month, month
+}
+
+// BudgetEvent records that the spend of a budget reached Threshold percent of
+// its limit in the period starting with PeriodStart. Every threshold is
+// recorded once per period. NotifiedAt is set once a notification has been
+// sent for it.
+type BudgetEvent struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	BudgetID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:uq_budget_events_threshold"`
+	PeriodStart YearMonth `gorm:"type:date;not null;uniqueIndex:uq_budget_events_threshold" swaggertype:"string" example:"07-2025"`
+	Threshold   int       `gorm:"not null;uniqueIndex:uq_budget_events_threshold" example:"80"`
+	Spend       int64     `gorm:"not null" example:"125000"`
+	Limit       int64     `gorm:"column:limit_amount;not null" example:"150000"`
+	Currency    Currency  `gorm:"type:char(3);not null" swaggertype:"string" example:"RUB"`
+	CreatedAt   time.Time
+	NotifiedAt  *time.Time
+}
+
+// Thresholds are percentages of a budget limit, stored as a Postgres integer
+// array.
+type Thresholds []int
+
+// Scan implements sql.Scanner for the text form of integer arrays, such as
+// {80,100}.
+func (t *Thresholds) Scan(src any) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("cannot scan %T into Thresholds", src)
+	}
+
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}")
+	thresholds := Thresholds{}
+	if value != "" {
+		for _, part := range strings.Split(value, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return fmt.Errorf("cannot scan %q into Thresholds: %w", value, err)
+			}
+			thresholds = append(thresholds, n)
+		}
+	}
+	*t = thresholds
+	return nil
+}
+
+// Value implements driver.Valuer, writing the array literal.
+func (t Thresholds) Value() (driver.Value, error) {
+	parts := make([]string, len(t))
+	for i, n := range t {
+		parts[i] = strconv.Itoa(n)
+	}
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Currency is an ISO 4217 alphabetic currency code. Amounts in the service
+// are stored in the currency's minor units (kopecks, cents, ...).
+type Currency string
+
+var ErrInvalidCurrency = errors.New("invalid currency, expected an ISO 4217 code")
+
+// currencyExponents maps supported ISO 4217 codes to the number of digits in
+// their minor unit.
+var currencyExponents = map[Currency]int{
+	"AED": 2, "AMD": 2, "ARS": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BHD": 3,
+	"BRL": 2, "BYN": 2, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "COP": 2,
+	"CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "GEL": 2, "HKD": 2,
+	"HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KGS": 2, "KRW": 0, "KWD": 3, "KZT": 2, "MXN": 2, "MYR": 2, "NOK": 2,
+	"NZD": 2, "OMR": 3, "PEN": 2, "PHP": 2, "PLN": 2, "RON": 2, "RSD": 2,
+	"RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TJS": 2, "TMT": 2,
+	"TND": 3, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "UZS": 2, "VND": 0,
+	"ZAR": 2,
+}
+
+// ParseCurrency upper-cases value and checks it is a supported ISO 4217 code.
+func ParseCurrency(value string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(value)))
+	if _, ok := currencyExponents[c]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, value)
+	}
+	return c, nil
+}
+
+// Exponent returns the number of minor-unit digits, 2 for unknown codes.
+func (c Currency) Exponent() int {
+	if exp, ok := currencyExponents[c]; ok {
+		return exp
+	}
+	return 2
+}
+
+// ConvertMinor converts amount minor units of from into minor units of to,
+// where one unit of from is worth rate units of to.
+func ConvertMinor(amount int64, from, to Currency, rate float64) int64 {
+	major := float64(amount) / math.Pow10(from.Exponent())
+	return int64(math.Round(major * rate * math.Pow10(to.Exponent())))
+}
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	parsed, err := ParseCurrency(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
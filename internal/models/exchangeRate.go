@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRate says that on RateDate one unit of BaseCurrency was worth Rate
+// units of QuoteCurrency.
+type ExchangeRate struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	RateDate      time.Time `gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_date_pair"`
+	BaseCurrency  Currency  `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_date_pair" swaggertype:"string" example:"USD"`
+	QuoteCurrency Currency  `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_date_pair" swaggertype:"string" example:"RUB"`
+	Rate          float64   `gorm:"type:numeric(20,10);not null;check:rate > 0" example:"92.5"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a write request sent with an
+// Idempotency-Key header, so that a retry gets the same response instead of
+// repeating the write. StatusCode is 0 while the first request is still
+// being processed. ResponseHeaders holds the replayed response headers as a
+// JSON object of value lists.
+type IdempotencyKey struct {
+	Key             string `gorm:"type:varchar(255);primaryKey"`
+	Method          string `gorm:"type:varchar(16);not null"`
+	Path            string `gorm:"type:text;not null"`
+	RequestHash     string `gorm:"type:char(64);not null"`
+	StatusCode      int    `gorm:"not null;default:0"`
+	ContentType     string `gorm:"type:text;not null;default:''"`
+	ResponseBody    []byte `gorm:"type:bytea"`
+	ResponseHeaders []byte `gorm:"type:jsonb"`
+	CreatedAt       time.Time
+	ExpiresAt       time.Time `gorm:"not null;index"`
+}
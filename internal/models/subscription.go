@@ -1,18 +1,107 @@
 package models
 
 import (
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Subscription struct {
-	ID          uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	ServiceName string    `gorm:"type:varchar(255);not null"`
-	Price       int       `gorm:"not null;check:price >= 0"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null"`
-	StartDate   string    `gorm:"type:varchar(7);not null"`
-	EndDate     *string   `gorm:"type:varchar(7)"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID               uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceName      string        `gorm:"type:varchar(255);not null"`
+	Category         *string       `gorm:"type:varchar(64)" example:"streaming"`
+	Price            int64         `gorm:"not null;check:price >= 0" example:"39900"`
+	Currency         Currency      `gorm:"type:char(3);not null;default:RUB" swaggertype:"string" example:"RUB"`
+	BillingPeriod    BillingPeriod `gorm:"type:varchar(32);not null;default:monthly" swaggertype:"string" example:"monthly"`
+	UserID           uuid.UUID     `gorm:"type:uuid;not null"`
+	StartDate        YearMonth     `gorm:"type:date;not null" swaggertype:"string" example:"07-2025"`
+	EndDate          *YearMonth    `gorm:"type:date" swaggertype:"string" example:"12-2025"`
+	TrialEnd         *YearMonth    `gorm:"type:date" swaggertype:"string" example:"08-2025"`
+	TrialConvertedAt *time.Time
+	Status           SubscriptionStatus  `gorm:"type:varchar(16);not null;default:active" swaggertype:"string" example:"active"`
+	Pauses           []SubscriptionPause `gorm:"foreignKey:SubscriptionID"`
+	Prices           []SubscriptionPrice `gorm:"foreignKey:SubscriptionID" json:"-"`
+	Version          int64               `gorm:"not null;default:1" example:"1"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index" swaggertype:"string" format:"date-time"`
+}
+
+// BillingStart is the month of the first paid charge: the month after the
+// trial when there is one, otherwise the start month.
+func (s Subscription) BillingStart() YearMonth {
+	if s.TrialEnd != nil && !s.TrialEnd.Before(s.StartDate) {
+		return s.TrialEnd.AddMonths(1)
+	}
+	return s.StartDate
+}
+
+// IsPausedIn reports whether month falls inside one of the pauses.
+func (s Subscription) IsPausedIn(month YearMonth) bool {
+	for _, pause := range s.Pauses {
+		if pause.Covers(month) {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceIn returns the price in force during month according to the price
+// history. Months before the first entry use the earliest known price, and
+// Price is used when no history is loaded.
+func (s Subscription) PriceIn(month YearMonth) int64 {
+	var inForce, earliest *SubscriptionPrice
+	for i := range s.Prices {
+		p := &s.Prices[i]
+		if earliest == nil || p.EffectiveFrom.Before(earliest.EffectiveFrom) {
+			earliest = p
+		}
+		if !p.EffectiveFrom.After(month) && (inForce == nil || p.EffectiveFrom.After(inForce.EffectiveFrom)) {
+			inForce = p
+		}
+	}
+
+	switch {
+	case inForce != nil:
+		return inForce.Price
+	case earliest != nil:
+		return earliest.Price
+	default:
+		return s.Price
+	}
+}
+
+// OpenPause returns the pause that has not been resumed yet, if any.
+func (s *Subscription) OpenPause() *SubscriptionPause {
+	for i := range s.Pauses {
+		if s.Pauses[i].ResumedFrom == nil {
+			return &s.Pauses[i]
+		}
+	}
+	return nil
+}
+
+// Overlaps reports whether s runs in at least one month of the period from
+// startDate to endDate. A missing end date runs indefinitely.
+func (s Subscription) Overlaps(startDate YearMonth, endDate *YearMonth) bool {
+	if s.EndDate != nil && s.EndDate.Before(startDate) {
+		return false
+	}
+	return endDate == nil || !endDate.Before(s.StartDate)
+}
+
+// NormalizeServiceName reduces a service name to its lower-case letters and
+// digits, so that "Yandex Plus", "yandex plus" and "Yandex-Plus" compare
+// equal.
+func NormalizeServiceName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionPrice is the price a subscription charges from EffectiveFrom
+// until the next entry takes over.
+type SubscriptionPrice struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_subscription_prices_effective"`
+	Price          int64     `gorm:"not null;check:price >= 0" example:"44900"`
+	EffectiveFrom  YearMonth `gorm:"type:date;not null;uniqueIndex:idx_subscription_prices_effective" swaggertype:"string" example:"01-2026"`
+	CreatedAt      time.Time
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionStatus string
+
+const (
+	StatusTrial     SubscriptionStatus = "trial"
+	StatusActive    SubscriptionStatus = "active"
+	StatusPaused    SubscriptionStatus = "paused"
+	StatusCancelled SubscriptionStatus = "cancelled"
+)
+
+type SubscriptionAction string
+
+const (
+	ActionPause      SubscriptionAction = "pause"
+	ActionResume     SubscriptionAction = "resume"
+	ActionCancel     SubscriptionAction = "cancel"
+	ActionReactivate SubscriptionAction = "reactivate"
+	// ActionConvert is applied automatically when a trial ends.
+	ActionConvert SubscriptionAction = "convert"
+)
+
+// subscriptionTransitions lists, per action, the statuses it may be applied
+// to and the status it leads to.
+var subscriptionTransitions = map[SubscriptionAction]struct {
+	from []SubscriptionStatus
+	to   SubscriptionStatus
+}{
+	ActionPause:      {from: []SubscriptionStatus{StatusActive}, to: StatusPaused},
+	ActionResume:     {from: []SubscriptionStatus{StatusPaused}, to: StatusActive},
+	ActionCancel:     {from: []SubscriptionStatus{StatusTrial, StatusActive, StatusPaused}, to: StatusCancelled},
+	ActionReactivate: {from: []SubscriptionStatus{StatusCancelled}, to: StatusActive},
+	ActionConvert:    {from: []SubscriptionStatus{StatusTrial}, to: StatusActive},
+}
+
+// Transition returns the status reached by applying action to s, and false
+// when the state machine does not allow it.
+func (s SubscriptionStatus) Transition(action SubscriptionAction) (SubscriptionStatus, bool) {
+	t, ok := subscriptionTransitions[action]
+	if !ok {
+		return "", false
+	}
+	for _, from := range t.from {
+		if from == s {
+			return t.to, true
+		}
+	}
+	return "", false
+}
+
+// SubscriptionStatusChange is one recorded lifecycle transition.
+// PreviousEndDate keeps the end date a cancellation replaced, so that
+// reactivating can restore it.
+type SubscriptionStatusChange struct {
+	ID              uuid.UUID          `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	SubscriptionID  uuid.UUID          `gorm:"type:uuid;not null;index"`
+	Action          SubscriptionAction `gorm:"type:varchar(16);not null" swaggertype:"string" example:"pause"`
+	FromStatus      SubscriptionStatus `gorm:"type:varchar(16);not null" swaggertype:"string" example:"active"`
+	ToStatus        SubscriptionStatus `gorm:"type:varchar(16);not null" swaggertype:"string" example:"paused"`
+	EffectiveMonth  YearMonth          `gorm:"type:date;not null" swaggertype:"string" example:"08-2025"`
+	PreviousEndDate *YearMonth         `gorm:"type:date" swaggertype:"string" example:"12-2025"`
+	Reason          string             `gorm:"type:text;not null"`
+	CreatedAt       time.Time
+}
+
+// SubscriptionPause is an interval during which a subscription is not
+// charged: from PausedFrom up to, but not including, ResumedFrom.
+type SubscriptionPause struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index"`
+	PausedFrom     YearMonth  `gorm:"type:date;not null" swaggertype:"string" example:"08-2025"`
+	ResumedFrom    *YearMonth `gorm:"type:date" swaggertype:"string" example:"10-2025"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Covers reports whether month falls inside the pause.
+func (p SubscriptionPause) Covers(month YearMonth) bool {
+	if month.Before(p.PausedFrom) {
+		return false
+	}
+	return p.ResumedFrom == nil || month.Before(*p.ResumedFrom)
+}
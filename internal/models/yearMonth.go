@@ -0,0 +1,185 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	yearMonthLayout    = "01-2006"
+	isoYearMonthLayout = "2006-01"
+)
+
+var (
+	ErrInvalidYearMonth    = errors.New("invalid year-month, expected MM-YYYY")
+	ErrInvalidISOYearMonth = errors.New("invalid year-month, expected YYYY-MM")
+)
+
+// YearMonth is a calendar month without a day component. It is exchanged as
+// MM-YYYY in JSON and stored as the first day of the month in a DATE column.
+// The zero value represents "no month" and is stored as NULL.
+type YearMonth struct {
+	year  int
+	month time.Month
+}
+
+func NewYearMonth(year int, month time.Month) YearMonth {
+	return YearMonthOf(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// YearMonthOf returns the month that t falls in, in t's own location.
+func YearMonthOf(t time.Time) YearMonth {
+	return YearMonth{year: t.Year(), month: t.Month()}
+}
+
+// CurrentMonth is the calendar month of the current UTC time.
+func CurrentMonth() YearMonth {
+	return YearMonthOf(time.Now().UTC())
+}
+
+func ParseYearMonth(value string) (YearMonth, error) {
+	t, err := time.Parse(yearMonthLayout, strings.TrimSpace(value))
+	if err != nil {
+		return YearMonth{}, fmt.Errorf("%w: %q", ErrInvalidYearMonth, value)
+	}
+	return YearMonthOf(t), nil
+}
+
+// ParseISOYearMonth parses the ISO 8601 form YYYY-MM used by API v2.
+func ParseISOYearMonth(value string) (YearMonth, error) {
+	t, err := time.Parse(isoYearMonthLayout, strings.TrimSpace(value))
+	if err != nil {
+		return YearMonth{}, fmt.Errorf("%w: %q", ErrInvalidISOYearMonth, value)
+	}
+	return YearMonthOf(t), nil
+}
+
+func (ym YearMonth) Year() int { return ym.year }
+
+func (ym YearMonth) Month() time.Month { return ym.month }
+
+func (ym YearMonth) IsZero() bool { return ym.year == 0 && ym.month == 0 }
+
+// Time returns midnight UTC on the first day of the month.
+func (ym YearMonth) Time() time.Time {
+	return time.Date(ym.year, ym.month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// LastDay returns midnight UTC on the last day of the month.
+func (ym YearMonth) LastDay() time.Time {
+	return ym.AddMonths(1).Time().AddDate(0, 0, -1)
+}
+
+func (ym YearMonth) String() string {
+	if ym.IsZero() {
+		return ""
+	}
+	return ym.Time().Format(yearMonthLayout)
+}
+
+// ISOString formats ym as YYYY-MM, or "" for the zero month.
+func (ym YearMonth) ISOString() string {
+	if ym.IsZero() {
+		return ""
+	}
+	return ym.Time().Format(isoYearMonthLayout)
+}
+
+func (ym YearMonth) AddMonths(n int) YearMonth {
+	return YearMonthOf(ym.Time().AddDate(0, n, 0))
+}
+
+// MonthsUntil returns the number of months from ym to other, negative when
+// other is earlier. 07-2025 to 09-2025 is 2.
+func (ym YearMonth) MonthsUntil(other YearMonth) int {
+	return other.index() - ym.index()
+}
+
+func (ym YearMonth) Compare(other YearMonth) int {
+	switch {
+	case ym.index() < other.index():
+		return -1
+	case ym.index() > other.index():
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (ym YearMonth) Before(other YearMonth) bool { return ym.Compare(other) < 0 }
+
+func (ym YearMonth) After(other YearMonth) bool { return ym.Compare(other) > 0 }
+
+func (ym YearMonth) index() int {
+	return ym.year*12 + int(ym.month) - 1
+}
+
+func (ym YearMonth) MarshalJSON() ([]byte, error) {
+	if ym.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ym.String())
+}
+
+func (ym *YearMonth) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidYearMonth, data)
+	}
+	return ym.UnmarshalText([]byte(value))
+}
+
+func (ym YearMonth) MarshalText() ([]byte, error) {
+	return []byte(ym.String()), nil
+}
+
+func (ym *YearMonth) UnmarshalText(text []byte) error {
+	parsed, err := ParseYearMonth(string(text))
+	if err != nil {
+		return err
+	}
+	*ym = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DATE columns. Strings are accepted in both
+// the ISO date form returned by text protocols and the legacy MM-YYYY form.
+func (ym *YearMonth) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*ym = YearMonth{}
+		return nil
+	case time.Time:
+		*ym = YearMonthOf(v)
+		return nil
+	case []byte:
+		return ym.scanString(string(v))
+	case string:
+		return ym.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into YearMonth", src)
+	}
+}
+
+func (ym *YearMonth) scanString(value string) error {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		*ym = YearMonthOf(t)
+		return nil
+	}
+	return ym.UnmarshalText([]byte(value))
+}
+
+// Value implements driver.Valuer, storing the first day of the month.
+func (ym YearMonth) Value() (driver.Value, error) {
+	if ym.IsZero() {
+		return nil, nil
+	}
+	return ym.Time(), nil
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseYearMonth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    YearMonth
+		wantErr bool
+	}{
+		{value: "07-2025", want: NewYearMonth(2025, time.July)},
+		{value: " 12-1999 ", want: NewYearMonth(1999, time.December)},
+		{value: "01-2026", want: NewYearMonth(2026, time.January)},
+		{value: "2025-07", wantErr: true},
+		{value: "13-2025", wantErr: true},
+		{value: "7-2025", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseYearMonth(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidYearMonth) {
+					t.Fatalf("ParseYearMonth(%q) error = %v, want ErrInvalidYearMonth", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseYearMonth(%q) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseYearMonth(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseISOYearMonth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    YearMonth
+		wantErr bool
+	}{
+		{value: "2025-07", want: NewYearMonth(2025, time.July)},
+		{value: "1999-12", want: NewYearMonth(1999, time.December)},
+		{value: "07-2025", wantErr: true},
+		{value: "2025-13", wantErr: true},
+		{value: "2025-07-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseISOYearMonth(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidISOYearMonth) {
+					t.Fatalf("ParseISOYearMonth(%q) error = %v, want ErrInvalidISOYearMonth", tt.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseISOYearMonth(%q) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseISOYearMonth(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearMonthArithmetic(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       YearMonth
+		months     int
+		want       YearMonth
+		monthsTill int
+	}{
+		{name: "same year", from: NewYearMonth(2025, time.July), months: 2, want: NewYearMonth(2025, time.September), monthsTill: 2},
+		{name: "into next year", from: NewYearMonth(2025, time.November), months: 3, want: NewYearMonth(2026, time.February), monthsTill: 3},
+		{name: "backwards over a year", from: NewYearMonth(2025, time.January), months: -1, want: NewYearMonth(2024, time.December), monthsTill: -1},
+		{name: "none", from: NewYearMonth(2025, time.March), months: 0, want: NewYearMonth(2025, time.March), monthsTill: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.from.AddMonths(tt.months)
+			if got != tt.want {
+				t.Errorf("%v.AddMonths(%d) = %v, want %v", tt.from, tt.months, got, tt.want)
+			}
+			if n := tt.from.MonthsUntil(got); n != tt.monthsTill {
+				t.Errorf("%v.MonthsUntil(%v) = %d, want %d", tt.from, got, n, tt.monthsTill)
+			}
+		})
+	}
+}
+
+func TestYearMonthLastDay(t *testing.T) {
+	tests := []struct {
+		month YearMonth
+		want  time.Time
+	}{
+		{month: NewYearMonth(2025, time.January), want: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{month: NewYearMonth(2024, time.February), want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{month: NewYearMonth(2025, time.February), want: time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)},
+		{month: NewYearMonth(2025, time.December), want: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.month.String(), func(t *testing.T) {
+			if got := tt.month.LastDay(); !got.Equal(tt.want) {
+				t.Errorf("LastDay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearMonthScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    YearMonth
+		wantErr bool
+	}{
+		{name: "nil", src: nil, want: YearMonth{}},
+		{name: "time", src: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), want: NewYearMonth(2025, time.July)},
+		{name: "time mid-month", src: time.Date(2025, time.July, 17, 13, 0, 0, 0, time.UTC), want: NewYearMonth(2025, time.July)},
+		{name: "ISO date string", src: "2025-07-01", want: NewYearMonth(2025, time.July)},
+		{name: "ISO date bytes", src: []byte("2025-12-01"), want: NewYearMonth(2025, time.December)},
+		{name: "legacy string", src: "07-2025", want: NewYearMonth(2025, time.July)},
+		{name: "invalid string", src: "July 2025", wantErr: true},
+		{name: "unsupported type", src: int64(202507), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewYearMonth(2000, time.January)
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %v, want an error", tt.src, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) error = %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan(%v) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearMonthValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		month YearMonth
+		want  any
+	}{
+		{name: "zero is NULL", month: YearMonth{}, want: nil},
+		{name: "first day of the month", month: NewYearMonth(2025, time.July), want: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.month.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYearMonthJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    YearMonth
+		wantErr bool
+	}{
+		{name: "month", json: `"07-2025"`, want: NewYearMonth(2025, time.July)},
+		{name: "null", json: `null`, want: YearMonth{}},
+		{name: "ISO form", json: `"2025-07"`, wantErr: true},
+		{name: "number", json: `202507`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got YearMonth
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidYearMonth) {
+					t.Fatalf("Unmarshal(%s) error = %v, want ErrInvalidYearMonth", tt.json, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", tt.json, err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal(%s) = %v, want %v", tt.json, got, tt.want)
+			}
+
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal(%v) error = %v", got, err)
+			}
+			if string(data) != tt.json {
+				t.Errorf("Marshal(%v) = %s, want %s", got, data, tt.json)
+			}
+		})
+	}
+}
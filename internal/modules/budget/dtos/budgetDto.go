@@ -0,0 +1,65 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+type CreateBudgetRequest struct {
+	UserID      uuid.UUID           `json:"user_id" validate:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Period      models.BudgetPeriod `json:"period" validate:"required,oneof=monthly yearly" swaggertype:"string" example:"monthly"`
+	Limit       int64               `json:"limit" validate:"gt=0" example:"150000"`
+	Currency    models.Currency     `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	ServiceName *string             `json:"service_name,omitempty" validate:"omitempty,servicename" example:"Yandex Plus"`
+	Category    *string             `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Thresholds  []int               `json:"thresholds,omitempty" validate:"omitempty,max=10,unique,dive,gt=0,lte=1000" example:"80,100"`
+}
+
+// UpdateBudgetRequest changes the fields that are present. An empty
+// service_name or category removes that scope.
+type UpdateBudgetRequest struct {
+	Period      *models.BudgetPeriod `json:"period,omitempty" validate:"omitempty,oneof=monthly yearly" swaggertype:"string" example:"yearly"`
+	Limit       *int64               `json:"limit,omitempty" validate:"omitempty,gt=0" example:"1500000"`
+	Currency    *models.Currency     `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	ServiceName *string              `json:"service_name,omitempty" validate:"omitempty,servicename" example:"Yandex Plus"`
+	Category    *string              `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Thresholds  []int                `json:"thresholds,omitempty" validate:"omitempty,max=10,unique,dive,gt=0,lte=1000" example:"50,90,100"`
+}
+
+// BudgetStatus compares the spend of the current period with the limit.
+// Spent counts the charges up to and including the current month, Projected
+// those of the whole period.
+type BudgetStatus struct {
+	BudgetID             uuid.UUID         `json:"budget_id" example:"3f1c2b7e-8a9d-4f6e-b5c4-2d1e0f9a8b7c"`
+	Period               string            `json:"period" example:"monthly"`
+	PeriodStart          string            `json:"period_start" example:"07-2025"`
+	PeriodEnd            string            `json:"period_end" example:"07-2025"`
+	Currency             string            `json:"currency" example:"RUB"`
+	Limit                int64             `json:"limit" example:"150000"`
+	Spent                int64             `json:"spent" example:"125000"`
+	Projected            int64             `json:"projected" example:"125000"`
+	Remaining            int64             `json:"remaining" example:"25000"`
+	Utilization          float64           `json:"utilization" example:"83.33"`
+	ProjectedUtilization float64           `json:"projected_utilization" example:"83.33"`
+	Thresholds           []ThresholdStatus `json:"thresholds"`
+}
+
+// ThresholdStatus says whether a threshold is reached in the current period.
+// ReachedAt is when its event was recorded.
+type ThresholdStatus struct {
+	Threshold int        `json:"threshold" example:"80"`
+	Amount    int64      `json:"amount" example:"120000"`
+	Reached   bool       `json:"reached" example:"true"`
+	ReachedAt *time.Time `json:"reached_at,omitempty"`
+}
+
+// EventFilter selects budget events; zero fields do not filter.
+type EventFilter struct {
+	BudgetID *uuid.UUID
+	UserID   string
+	// Pending selects events that have not been notified yet.
+	Pending bool
+}
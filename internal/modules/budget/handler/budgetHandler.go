@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/service"
+)
+
+type BudgetHandler struct {
+	service service.BudgetService
+}
+
+func NewBudgetHandler(service service.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+// CreateBudget godoc
+// @Summary Create budget
+// @Description Create a monthly or yearly spending limit for a user, optionally for one service or category. Thresholds are percentages of the limit, 80 and 100 by default.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body dtos.CreateBudgetRequest true "Budget"
+// @Success 201 {object} models.Budget
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets [post]
+func (h *BudgetHandler) Create(c echo.Context) error {
+	var req dtos.CreateBudgetRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	budget, err := h.service.Create(req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, budget)
+}
+
+// GetBudget godoc
+// @Summary Get budget
+// @Tags budgets
+// @Produce json
+// @Param id path string true "Budget ID (UUID)"
+// @Success 200 {object} models.Budget
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/{id} [get]
+func (h *BudgetHandler) Get(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	budget, err := h.service.Get(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, budget)
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Tags budgets
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets [get]
+func (h *BudgetHandler) List(c echo.Context) error {
+	userID := c.QueryParam("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return apperr.BadRequest("invalid user_id")
+		}
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	budgets, meta, err := h.service.List(userID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": budgets,
+		"meta": meta,
+	})
+}
+
+// UpdateBudget godoc
+// @Summary Update budget
+// @Description Change the fields that are present; an empty service_name or category removes that scope
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path string true "Budget ID (UUID)"
+// @Param budget body dtos.UpdateBudgetRequest true "Fields to change"
+// @Success 200 {object} models.Budget
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/{id} [put]
+func (h *BudgetHandler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	var req dtos.UpdateBudgetRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	budget, err := h.service.Update(id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, budget)
+}
+
+// DeleteBudget godoc
+// @Summary Delete budget
+// @Description Delete a budget together with its events
+// @Tags budgets
+// @Param id path string true "Budget ID (UUID)"
+// @Success 204
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/{id} [delete]
+func (h *BudgetHandler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// BudgetStatus godoc
+// @Summary Budget status
+// @Description Compare the spend of the current month or year with the limit. spent counts the charges up to and including the current month, projected those of the whole period; thresholds are reached by the projected spend.
+// @Tags budgets
+// @Produce json
+// @Param id path string true "Budget ID (UUID)"
+// @Success 200 {object} dtos.BudgetStatus
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/{id}/status [get]
+func (h *BudgetHandler) Status(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	status, err := h.service.Status(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// ListBudgetEvents godoc
+// @Summary List budget threshold events
+// @Description List the thresholds reached by budgets, oldest first. Notifiers poll with pending=true and mark each event notified once it is sent.
+// @Tags budgets
+// @Produce json
+// @Param budget_id query string false "Budget ID (UUID)"
+// @Param user_id query string false "User ID (UUID)"
+// @Param pending query bool false "Only events that have not been notified"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/events [get]
+func (h *BudgetHandler) Events(c echo.Context) error {
+	var filter dtos.EventFilter
+	if value := c.QueryParam("budget_id"); value != "" {
+		budgetID, err := uuid.Parse(value)
+		if err != nil {
+			return apperr.BadRequest("invalid budget_id")
+		}
+		filter.BudgetID = &budgetID
+	}
+	if value := c.QueryParam("user_id"); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			return apperr.BadRequest("invalid user_id")
+		}
+		filter.UserID = value
+	}
+	if value := c.QueryParam("pending"); value != "" {
+		pending, err := strconv.ParseBool(value)
+		if err != nil {
+			return apperr.BadRequest("pending must be true or false")
+		}
+		filter.Pending = pending
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	events, meta, err := h.service.Events(filter, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": events,
+		"meta": meta,
+	})
+}
+
+// MarkBudgetEventNotified godoc
+// @Summary Mark budget event notified
+// @Description Record that a notification was sent for an event, so it is no longer pending. Marking it again keeps the first time.
+// @Tags budgets
+// @Produce json
+// @Param id path string true "Budget event ID (UUID)"
+// @Success 200 {object} models.BudgetEvent
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/budgets/events/{id}/notified [post]
+func (h *BudgetHandler) MarkNotified(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	event, err := h.service.MarkNotified(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, event)
+}
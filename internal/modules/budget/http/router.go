@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/cmd"
+	"github.com/Ilmyrat1822/subs/internal/deprecation"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/handler"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/repository"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/service"
+)
+
+// InitBudgetRouter registers the budget endpoints and returns the service so
+// the subscription module can report spend changes to it.
+func InitBudgetRouter(server *cmd.Server, spending service.Spending, legacy deprecation.Window) service.BudgetService {
+	budgetRepository := repository.NewBudgetRepository(server.Database)
+	budgetService := service.NewBudgetService(budgetRepository, spending, server.Config.DefaultCurrency)
+	budgetHandler := handler.NewBudgetHandler(budgetService)
+
+	server.Jobs.Every("budget-evaluation", server.Config.BudgetEvaluationInterval, func(ctx context.Context) error {
+		_, err := budgetService.EvaluateAll()
+		return err
+	})
+
+	// Budgets are part of API v1; /api/budgets is the deprecated unversioned
+	// path, like /api/subs. v2 has no budgets yet, so it has no sunset.
+	registerV1(server.Echo.Group("/api/v1/budgets"), budgetHandler)
+	registerV1(
+		server.Echo.Group("/api/budgets", legacy.WithoutSunset().Middleware("/api/budgets", "/api/v1/budgets")),
+		budgetHandler,
+	)
+
+	return budgetService
+}
+
+func registerV1(budgetRouter *echo.Group, budgetHandler *handler.BudgetHandler) {
+	budgetRouter.GET("", budgetHandler.List)
+	budgetRouter.POST("", budgetHandler.Create)
+	budgetRouter.GET("/events", budgetHandler.Events)
+	budgetRouter.POST("/events/:id/notified", budgetHandler.MarkNotified)
+	budgetRouter.GET("/:id", budgetHandler.Get)
+	budgetRouter.PUT("/:id", budgetHandler.Update)
+	budgetRouter.DELETE("/:id", budgetHandler.Delete)
+	budgetRouter.GET("/:id/status", budgetHandler.Status)
+}
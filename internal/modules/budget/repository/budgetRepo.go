@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/dtos"
+)
+
+type BudgetRepository interface {
+	Create(budget *models.Budget) error
+	GetByID(id uuid.UUID) (*models.Budget, error)
+	List(userID string, limit, offset int) ([]models.Budget, int64, error)
+	ListByUser(userID uuid.UUID) ([]models.Budget, error)
+	All() ([]models.Budget, error)
+	Update(budget *models.Budget) error
+	Delete(id uuid.UUID) (bool, error)
+	RecordEvent(event *models.BudgetEvent) (bool, error)
+	PeriodEvents(budgetID uuid.UUID, periodStart models.YearMonth) ([]models.BudgetEvent, error)
+	ListEvents(filter dtos.EventFilter, limit, offset int) ([]models.BudgetEvent, int64, error)
+	MarkNotified(id uuid.UUID, at time.Time) (*models.BudgetEvent, error)
+}
+
+type budgetRepository struct {
+	db *gorm.DB
+}
+
+func NewBudgetRepository(db *gorm.DB) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+func (r *budgetRepository) Create(budget *models.Budget) error {
+	return r.db.Create(budget).Error
+}
+
+func (r *budgetRepository) GetByID(id uuid.UUID) (*models.Budget, error) {
+	var budget models.Budget
+	if err := r.db.First(&budget, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *budgetRepository) List(userID string, limit, offset int) ([]models.Budget, int64, error) {
+	var budgets []models.Budget
+	var total int64
+
+	query := r.db.Model(&models.Budget{})
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("created_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&budgets).Error
+
+	return budgets, total, err
+}
+
+func (r *budgetRepository) ListByUser(userID uuid.UUID) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Where("user_id = ?", userID).Order("created_at, id").Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) All() ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Order("user_id, created_at, id").Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepository) Update(budget *models.Budget) error {
+	return r.db.Save(budget).Error
+}
+
+func (r *budgetRepository) Delete(id uuid.UUID) (bool, error) {
+	res := r.db.Delete(&models.Budget{}, "id = ?", id)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// RecordEvent stores event unless its threshold was already recorded for the
+// same budget and period, and reports whether it was stored.
+func (r *budgetRepository) RecordEvent(event *models.BudgetEvent) (bool, error) {
+	res := r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "budget_id"}, {Name: "period_start"}, {Name: "threshold"}},
+			DoNothing: true,
+		}).
+		Create(event)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// PeriodEvents returns the events of one budget period, lowest threshold
+// first.
+func (r *budgetRepository) PeriodEvents(budgetID uuid.UUID, periodStart models.YearMonth) ([]models.BudgetEvent, error) {
+	var events []models.BudgetEvent
+	err := r.db.
+		Where("budget_id = ? AND period_start = ?", budgetID, periodStart).
+		Order("threshold").
+		Find(&events).Error
+	return events, err
+}
+
+// ListEvents returns events oldest first, so notifications go out in the
+// order the thresholds were reached.
+func (r *budgetRepository) ListEvents(filter dtos.EventFilter, limit, offset int) ([]models.BudgetEvent, int64, error) {
+	var events []models.BudgetEvent
+	var total int64
+
+	query := r.db.Model(&models.BudgetEvent{})
+	if filter.BudgetID != nil {
+		query = query.Where("budget_id = ?", *filter.BudgetID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("budget_id IN (?)", r.db.Model(&models.Budget{}).Select("id").Where("user_id = ?", filter.UserID))
+	}
+	if filter.Pending {
+		query = query.Where("notified_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("created_at, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+
+	return events, total, err
+}
+
+// MarkNotified sets the notification time of an event that has none yet and
+// returns the event. It returns gorm.ErrRecordNotFound for unknown ids.
+func (r *budgetRepository) MarkNotified(id uuid.UUID, at time.Time) (*models.BudgetEvent, error) {
+	if err := r.db.Model(&models.BudgetEvent{}).
+		Where("id = ? AND notified_at IS NULL", id).
+		Update("notified_at", at).Error; err != nil {
+		return nil, err
+	}
+
+	var event models.BudgetEvent
+	if err := r.db.First(&event, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
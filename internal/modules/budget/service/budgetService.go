@@ -0,0 +1,282 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/repository"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
+)
+
+type BudgetService interface {
+	Create(req dtos.CreateBudgetRequest) (*models.Budget, error)
+	Get(id uuid.UUID) (*models.Budget, error)
+	List(userID string, limit, offset int) ([]models.Budget, *pagination.Meta, error)
+	Update(id uuid.UUID, req dtos.UpdateBudgetRequest) (*models.Budget, error)
+	Delete(id uuid.UUID) error
+	Status(id uuid.UUID) (*dtos.BudgetStatus, error)
+	Events(filter dtos.EventFilter, limit, offset int) ([]models.BudgetEvent, *pagination.Meta, error)
+	MarkNotified(id uuid.UUID) (*models.BudgetEvent, error)
+	SpendChanged(userID uuid.UUID)
+	EvaluateAll() (int, error)
+}
+
+// Spending calculates what a user's subscriptions, optionally only those to
+// one service or in one category, are charged from one month to another. It
+// is implemented by the subscription module.
+type Spending interface {
+	Spend(userID uuid.UUID, serviceName, category string, from, to models.YearMonth, currency models.Currency) (int64, error)
+}
+
+var (
+	ErrBudgetNotFound      = apperr.New(apperr.NotFound, "budget-not-found", "budget not found")
+	ErrBudgetEventNotFound = apperr.New(apperr.NotFound, "budget-event-not-found", "budget event not found")
+)
+
+type budgetService struct {
+	repo            repository.BudgetRepository
+	spending        Spending
+	defaultCurrency models.Currency
+}
+
+func NewBudgetService(
+	repo repository.BudgetRepository,
+	spending Spending,
+	defaultCurrency string,
+) BudgetService {
+	return &budgetService{
+		repo:            repo,
+		spending:        spending,
+		defaultCurrency: models.Currency(defaultCurrency),
+	}
+}
+
+func (s *budgetService) Create(req dtos.CreateBudgetRequest) (*models.Budget, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+
+	budget := &models.Budget{
+		UserID:      req.UserID,
+		Period:      req.Period,
+		Limit:       req.Limit,
+		Currency:    currency,
+		ServiceName: optional(req.ServiceName),
+		Category:    optional(req.Category),
+		Thresholds:  normalizeThresholds(req.Thresholds),
+	}
+	if err := s.repo.Create(budget); err != nil {
+		return nil, err
+	}
+
+	s.evaluateLogged(budget, models.CurrentMonth())
+	return budget, nil
+}
+
+// optional maps an empty scope to nil, so that it does not restrict the
+// budget.
+func optional(value *string) *string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	return &trimmed
+}
+
+// normalizeThresholds sorts thresholds, falling back to the defaults when
+// there are none.
+func normalizeThresholds(thresholds []int) models.Thresholds {
+	if len(thresholds) == 0 {
+		return slices.Clone(models.DefaultBudgetThresholds)
+	}
+	normalized := models.Thresholds(slices.Clone(thresholds))
+	slices.Sort(normalized)
+	return normalized
+}
+
+func (s *budgetService) Get(id uuid.UUID) (*models.Budget, error) {
+	budget, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *budgetService) List(userID string, limit, offset int) ([]models.Budget, *pagination.Meta, error) {
+	limit, offset = pagination.Normalize(limit, offset)
+
+	budgets, total, err := s.repo.List(userID, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return budgets, pagination.OffsetMeta(limit, offset, total), nil
+}
+
+func (s *budgetService) Update(id uuid.UUID, req dtos.UpdateBudgetRequest) (*models.Budget, error) {
+	budget, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Period != nil {
+		budget.Period = *req.Period
+	}
+	if req.Limit != nil {
+		budget.Limit = *req.Limit
+	}
+	if req.Currency != nil {
+		budget.Currency = *req.Currency
+	}
+	if req.ServiceName != nil {
+		budget.ServiceName = optional(req.ServiceName)
+	}
+	if req.Category != nil {
+		budget.Category = optional(req.Category)
+	}
+	if req.Thresholds != nil {
+		budget.Thresholds = normalizeThresholds(req.Thresholds)
+	}
+
+	if err := s.repo.Update(budget); err != nil {
+		return nil, err
+	}
+
+	s.evaluateLogged(budget, models.CurrentMonth())
+	return budget, nil
+}
+
+func (s *budgetService) Delete(id uuid.UUID) error {
+	found, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// Status reports the spend of the period containing the current month.
+func (s *budgetService) Status(id uuid.UUID) (*dtos.BudgetStatus, error) {
+	budget, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	month := models.CurrentMonth()
+	from, to := budget.PeriodAround(month)
+	spent, projected, err := s.spend(budget, month)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.PeriodEvents(budget.ID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dtos.BudgetStatus{
+		BudgetID:             budget.ID,
+		Period:               string(budget.Period),
+		PeriodStart:          from.String(),
+		PeriodEnd:            to.String(),
+		Currency:             string(budget.Currency),
+		Limit:                budget.Limit,
+		Spent:                spent,
+		Projected:            projected,
+		Remaining:            budget.Limit - spent,
+		Utilization:          percentOf(spent, budget.Limit),
+		ProjectedUtilization: percentOf(projected, budget.Limit),
+		Thresholds:           make([]dtos.ThresholdStatus, len(budget.Thresholds)),
+	}
+	for i, threshold := range budget.Thresholds {
+		status.Thresholds[i] = dtos.ThresholdStatus{
+			Threshold: threshold,
+			Amount:    thresholdAmount(budget.Limit, threshold),
+			Reached:   reached(projected, budget.Limit, threshold),
+		}
+		for _, event := range events {
+			if event.Threshold == threshold {
+				recordedAt := event.CreatedAt
+				status.Thresholds[i].ReachedAt = &recordedAt
+			}
+		}
+	}
+	return status, nil
+}
+
+// spend returns what budget has been charged from the start of its period up
+// to and including month, and what it will be charged over the whole period.
+func (s *budgetService) spend(budget *models.Budget, month models.YearMonth) (int64, int64, error) {
+	from, to := budget.PeriodAround(month)
+	var serviceName, category string
+	if budget.ServiceName != nil {
+		serviceName = *budget.ServiceName
+	}
+	if budget.Category != nil {
+		category = *budget.Category
+	}
+
+	spent, err := s.spending.Spend(budget.UserID, serviceName, category, from, month, budget.Currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !to.After(month) {
+		return spent, spent, nil
+	}
+	rest, err := s.spending.Spend(budget.UserID, serviceName, category, month.AddMonths(1), to, budget.Currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return spent, spent + rest, nil
+}
+
+// percentOf returns amount as a percentage of limit, rounded to 2 decimals.
+func percentOf(amount, limit int64) float64 {
+	return math.Round(float64(amount)*10000/float64(limit)) / 100
+}
+
+func thresholdAmount(limit int64, threshold int) int64 {
+	return limit * int64(threshold) / 100
+}
+
+func reached(spend, limit int64, threshold int) bool {
+	return spend*100 >= limit*int64(threshold)
+}
+
+func (s *budgetService) Events(filter dtos.EventFilter, limit, offset int) ([]models.BudgetEvent, *pagination.Meta, error) {
+	limit, offset = pagination.Normalize(limit, offset)
+
+	events, total, err := s.repo.ListEvents(filter, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return events, pagination.OffsetMeta(limit, offset, total), nil
+}
+
+// MarkNotified records that a notification was sent for an event. Marking
+// an event again keeps the first time.
+func (s *budgetService) MarkNotified(id uuid.UUID) (*models.BudgetEvent, error) {
+	event, err := s.repo.MarkNotified(id, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBudgetEventNotFound
+		}
+		return nil, err
+	}
+	return event, nil
+}
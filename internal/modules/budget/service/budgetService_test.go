@@ -0,0 +1,240 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/budget/repository"
+)
+
+// flatSpending charges perMonth for every month asked about and remembers
+// the scope of the last call.
+type flatSpending struct {
+	perMonth    int64
+	serviceName string
+	category    string
+}
+
+func (s *flatSpending) Spend(userID uuid.UUID, serviceName, category string, from, to models.YearMonth, currency models.Currency) (int64, error) {
+	s.serviceName, s.category = serviceName, category
+	return s.perMonth * int64(from.MonthsUntil(to)+1), nil
+}
+
+// memoryBudgets keeps budgets and events in memory. RecordEvent follows the
+// unique index of the real table: one event per budget, period and
+// threshold.
+type memoryBudgets struct {
+	repository.BudgetRepository
+	budgets map[uuid.UUID]models.Budget
+	events  []models.BudgetEvent
+}
+
+func newMemoryBudgets(budgets ...models.Budget) *memoryBudgets {
+	repo := &memoryBudgets{budgets: make(map[uuid.UUID]models.Budget)}
+	for _, budget := range budgets {
+		repo.budgets[budget.ID] = budget
+	}
+	return repo
+}
+
+func (r *memoryBudgets) Create(budget *models.Budget) error {
+	budget.ID = uuid.New()
+	r.budgets[budget.ID] = *budget
+	return nil
+}
+
+func (r *memoryBudgets) GetByID(id uuid.UUID) (*models.Budget, error) {
+	budget, ok := r.budgets[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &budget, nil
+}
+
+func (r *memoryBudgets) Update(budget *models.Budget) error {
+	r.budgets[budget.ID] = *budget
+	return nil
+}
+
+func (r *memoryBudgets) RecordEvent(event *models.BudgetEvent) (bool, error) {
+	for _, recorded := range r.events {
+		if recorded.BudgetID == event.BudgetID && recorded.PeriodStart == event.PeriodStart && recorded.Threshold == event.Threshold {
+			return false, nil
+		}
+	}
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now().UTC()
+	r.events = append(r.events, *event)
+	return true, nil
+}
+
+func (r *memoryBudgets) PeriodEvents(budgetID uuid.UUID, periodStart models.YearMonth) ([]models.BudgetEvent, error) {
+	var events []models.BudgetEvent
+	for _, event := range r.events {
+		if event.BudgetID == budgetID && event.PeriodStart == periodStart {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestStatus(t *testing.T) {
+	month := models.CurrentMonth()
+	elapsed := int64(month.Month())
+	netflix := "Netflix"
+
+	tests := []struct {
+		name        string
+		budget      models.Budget
+		perMonth    int64
+		want        dtos.BudgetStatus
+		wantReached []bool
+	}{
+		{
+			name: "monthly",
+			budget: models.Budget{
+				Period: models.BudgetMonthly, Limit: 150000, Currency: "RUB",
+				ServiceName: &netflix, Thresholds: models.Thresholds{50, 80, 100},
+			},
+			perMonth: 125000,
+			want: dtos.BudgetStatus{
+				Period: "monthly", PeriodStart: month.String(), PeriodEnd: month.String(),
+				Currency: "RUB", Limit: 150000, Spent: 125000, Projected: 125000, Remaining: 25000,
+				Utilization: 83.33, ProjectedUtilization: 83.33,
+			},
+			wantReached: []bool{true, true, false},
+		},
+		{
+			name: "yearly",
+			budget: models.Budget{
+				Period: models.BudgetYearly, Limit: 1200000, Currency: "USD",
+				Thresholds: models.Thresholds{100, 120},
+			},
+			perMonth: 100000,
+			want: dtos.BudgetStatus{
+				Period:      "yearly",
+				PeriodStart: models.NewYearMonth(month.Year(), time.January).String(),
+				PeriodEnd:   models.NewYearMonth(month.Year(), time.December).String(),
+				Currency:    "USD", Limit: 1200000, Spent: 100000 * elapsed, Projected: 1200000,
+				Remaining:   1200000 - 100000*elapsed,
+				Utilization: percentOf(100000*elapsed, 1200000), ProjectedUtilization: 100,
+			},
+			wantReached: []bool{true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.budget.ID = uuid.New()
+			spending := &flatSpending{perMonth: tt.perMonth}
+			svc := NewBudgetService(newMemoryBudgets(tt.budget), spending, "RUB")
+
+			got, err := svc.Status(tt.budget.ID)
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			thresholds := got.Thresholds
+			got.Thresholds = nil
+			tt.want.BudgetID = tt.budget.ID
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Status() = %+v, want %+v", *got, tt.want)
+			}
+			if tt.budget.ServiceName != nil && spending.serviceName != *tt.budget.ServiceName {
+				t.Errorf("spend asked for service %q, want %q", spending.serviceName, *tt.budget.ServiceName)
+			}
+
+			if len(thresholds) != len(tt.wantReached) {
+				t.Fatalf("Status() thresholds = %+v, want %d", thresholds, len(tt.wantReached))
+			}
+			for i, threshold := range thresholds {
+				if threshold.Threshold != tt.budget.Thresholds[i] || threshold.Reached != tt.wantReached[i] {
+					t.Errorf("threshold %d = %+v, want %d reached %v", i, threshold, tt.budget.Thresholds[i], tt.wantReached[i])
+				}
+				if want := tt.budget.Limit * int64(threshold.Threshold) / 100; threshold.Amount != want {
+					t.Errorf("threshold %d amount = %d, want %d", threshold.Threshold, threshold.Amount, want)
+				}
+				if threshold.ReachedAt != nil {
+					t.Errorf("threshold %d reached at %v before any event was recorded", threshold.Threshold, threshold.ReachedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusReportsRecordedEvents(t *testing.T) {
+	budget := models.Budget{
+		ID: uuid.New(), Period: models.BudgetMonthly, Limit: 1000, Currency: "RUB",
+		Thresholds: models.Thresholds{50, 100},
+	}
+	repo := newMemoryBudgets(budget)
+	svc := NewBudgetService(repo, &flatSpending{perMonth: 600}, "RUB")
+	if _, err := repo.RecordEvent(&models.BudgetEvent{BudgetID: budget.ID, PeriodStart: models.CurrentMonth().AddMonths(-1), Threshold: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.RecordEvent(&models.BudgetEvent{BudgetID: budget.ID, PeriodStart: models.CurrentMonth(), Threshold: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.Status(budget.ID)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if got.Thresholds[0].ReachedAt == nil {
+		t.Errorf("threshold 50 has no ReachedAt, want the time of its event")
+	}
+	if got.Thresholds[1].ReachedAt != nil {
+		t.Errorf("threshold 100 reached at %v, want nil: its event is from the previous period", got.Thresholds[1].ReachedAt)
+	}
+}
+
+func TestUpdateScope(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name         string
+		req          dtos.UpdateBudgetRequest
+		wantService  *string
+		wantCategory *string
+	}{
+		{name: "absent keeps the scope", req: dtos.UpdateBudgetRequest{}, wantService: text("Netflix"), wantCategory: text("streaming")},
+		{name: "empty service clears it", req: dtos.UpdateBudgetRequest{ServiceName: text("")}, wantService: nil, wantCategory: text("streaming")},
+		{name: "blank category clears it", req: dtos.UpdateBudgetRequest{Category: text("  ")}, wantService: text("Netflix"), wantCategory: nil},
+		{name: "both cleared", req: dtos.UpdateBudgetRequest{ServiceName: text(""), Category: text("")}, wantService: nil, wantCategory: nil},
+		{name: "new scope is trimmed", req: dtos.UpdateBudgetRequest{ServiceName: text(" Kion ")}, wantService: text("Kion"), wantCategory: text("streaming")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := models.Budget{
+				ID: uuid.New(), Period: models.BudgetMonthly, Limit: 1000, Currency: "RUB",
+				ServiceName: text("Netflix"), Category: text("streaming"), Thresholds: models.Thresholds{100},
+			}
+			repo := newMemoryBudgets(budget)
+			svc := NewBudgetService(repo, &flatSpending{}, "RUB")
+
+			if _, err := svc.Update(budget.ID, tt.req); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			stored := repo.budgets[budget.ID]
+			if !reflect.DeepEqual(stored.ServiceName, tt.wantService) {
+				t.Errorf("service name = %v, want %v", deref(stored.ServiceName), deref(tt.wantService))
+			}
+			if !reflect.DeepEqual(stored.Category, tt.wantCategory) {
+				t.Errorf("category = %v, want %v", deref(stored.Category), deref(tt.wantCategory))
+			}
+		})
+	}
+}
+
+func deref(value *string) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
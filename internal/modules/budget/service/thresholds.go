@@ -0,0 +1,89 @@
+package service
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// SpendChanged evaluates the budgets of userID after their subscriptions
+// changed. It implements the subscription module's SpendObserver, so
+// failures are logged rather than failing the change.
+func (s *budgetService) SpendChanged(userID uuid.UUID) {
+	budgets, err := s.repo.ListByUser(userID)
+	if err != nil {
+		log.Printf("Listing budgets of user %s failed: %v", userID, err)
+		return
+	}
+
+	month := models.CurrentMonth()
+	for i := range budgets {
+		s.evaluateLogged(&budgets[i], month)
+	}
+}
+
+// EvaluateAll evaluates every budget and returns the number of events
+// recorded. It catches thresholds reached without a subscription change,
+// such as at the start of a period.
+func (s *budgetService) EvaluateAll() (int, error) {
+	budgets, err := s.repo.All()
+	if err != nil {
+		return 0, err
+	}
+
+	month := models.CurrentMonth()
+	recorded := 0
+	for i := range budgets {
+		n, err := s.evaluate(&budgets[i], month)
+		if err != nil {
+			log.Printf("Evaluating budget %s failed: %v", budgets[i].ID, err)
+			continue
+		}
+		recorded += n
+	}
+	if recorded > 0 {
+		log.Printf("Recorded %d budget threshold events", recorded)
+	}
+	return recorded, nil
+}
+
+func (s *budgetService) evaluateLogged(budget *models.Budget, month models.YearMonth) {
+	if _, err := s.evaluate(budget, month); err != nil {
+		log.Printf("Evaluating budget %s failed: %v", budget.ID, err)
+	}
+}
+
+// evaluate records an event for every threshold that the projected spend of
+// the period containing month reaches and that has not been recorded for the
+// period yet. It returns the number of events recorded.
+func (s *budgetService) evaluate(budget *models.Budget, month models.YearMonth) (int, error) {
+	_, projected, err := s.spend(budget, month)
+	if err != nil {
+		return 0, err
+	}
+
+	from, _ := budget.PeriodAround(month)
+	recorded := 0
+	for _, threshold := range budget.Thresholds {
+		if !reached(projected, budget.Limit, threshold) {
+			continue
+		}
+		stored, err := s.repo.RecordEvent(&models.BudgetEvent{
+			BudgetID:    budget.ID,
+			PeriodStart: from,
+			Threshold:   threshold,
+			Spend:       projected,
+			Limit:       budget.Limit,
+			Currency:    budget.Currency,
+		})
+		if err != nil {
+			return recorded, err
+		}
+		if stored {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
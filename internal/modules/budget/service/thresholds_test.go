@@ -0,0 +1,116 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+func TestEvaluateThresholds(t *testing.T) {
+	july := models.NewYearMonth(2025, time.July)
+
+	tests := []struct {
+		name     string
+		perMonth int64
+		want     []int
+	}{
+		{name: "below every threshold", perMonth: 499, want: nil},
+		{name: "exactly the first", perMonth: 500, want: []int{50}},
+		{name: "just below the limit", perMonth: 999, want: []int{50, 80}},
+		{name: "at the limit", perMonth: 1000, want: []int{50, 80, 100}},
+		{name: "over the limit", perMonth: 1500, want: []int{50, 80, 100, 150}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := models.Budget{
+				ID: uuid.New(), Period: models.BudgetMonthly, Limit: 1000, Currency: "RUB",
+				Thresholds: models.Thresholds{50, 80, 100, 150},
+			}
+			repo := newMemoryBudgets(budget)
+			svc := NewBudgetService(repo, &flatSpending{perMonth: tt.perMonth}, "RUB").(*budgetService)
+
+			n, err := svc.evaluate(&budget, july)
+			if err != nil {
+				t.Fatalf("evaluate() error = %v", err)
+			}
+			var got []int
+			for _, event := range repo.events {
+				got = append(got, event.Threshold)
+				if event.PeriodStart != july || event.Spend != tt.perMonth || event.Limit != 1000 || event.Currency != "RUB" {
+					t.Errorf("event = %+v, want period %s with spend %d of 1000 RUB", event, july, tt.perMonth)
+				}
+			}
+			if n != len(tt.want) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluate() recorded %d: %v, want %v", n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateRecordsOncePerPeriod(t *testing.T) {
+	type step struct {
+		month    models.YearMonth
+		perMonth int64
+		want     int
+	}
+	july := models.NewYearMonth(2025, time.July)
+	august := models.NewYearMonth(2025, time.August)
+
+	tests := []struct {
+		name   string
+		period models.BudgetPeriod
+		limit  int64
+		steps  []step
+	}{
+		{
+			name:   "monthly",
+			period: models.BudgetMonthly,
+			limit:  1000,
+			steps: []step{
+				{month: july, perMonth: 800, want: 1},
+				{month: july, perMonth: 800, want: 0},
+				{month: july, perMonth: 1200, want: 1},
+				{month: july, perMonth: 1200, want: 0},
+				{month: august, perMonth: 1200, want: 2},
+			},
+		},
+		{
+			name:   "yearly",
+			period: models.BudgetYearly,
+			limit:  12000,
+			steps: []step{
+				{month: july, perMonth: 800, want: 1},
+				{month: august, perMonth: 800, want: 0},
+				{month: august, perMonth: 1000, want: 1},
+				{month: models.NewYearMonth(2026, time.January), perMonth: 1000, want: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := models.Budget{
+				ID: uuid.New(), Period: tt.period, Limit: tt.limit, Currency: "RUB",
+				Thresholds: models.Thresholds{80, 100},
+			}
+			spending := &flatSpending{}
+			svc := NewBudgetService(newMemoryBudgets(budget), spending, "RUB").(*budgetService)
+
+			for i, step := range tt.steps {
+				spending.perMonth = step.perMonth
+				n, err := svc.evaluate(&budget, step.month)
+				if err != nil {
+					t.Fatalf("step %d: evaluate() error = %v", i, err)
+				}
+				if n != step.want {
+					t.Errorf("step %d: evaluate(%s) at %d a month recorded %d, want %d", i, step.month, step.perMonth, n, step.want)
+				}
+			}
+		})
+	}
+}
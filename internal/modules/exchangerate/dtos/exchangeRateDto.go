@@ -0,0 +1,25 @@
+package dtos
+
+import "github.com/Ilmyrat1822/subs/internal/models"
+
+type UpsertExchangeRateRequest struct {
+	Date  string          `json:"date" validate:"required,datetime=2006-01-02" example:"2025-07-01"`
+	Base  models.Currency `json:"base" validate:"required" swaggertype:"string" example:"USD"`
+	Quote models.Currency `json:"quote" validate:"required,nefield=Base" swaggertype:"string" example:"RUB"`
+	Rate  float64         `json:"rate" validate:"gt=0" example:"92.5"`
+}
+
+type ImportResponse struct {
+	Imported int `json:"imported" example:"120"`
+}
+
+type ImportLineError struct {
+	Line  int    `json:"line" example:"3"`
+	Error string `json:"error" example:"invalid rate \"abc\""`
+}
+
+type PaginationMeta struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
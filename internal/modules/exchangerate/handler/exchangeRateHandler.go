@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/service"
+)
+
+type ExchangeRateHandler struct {
+	service service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(service service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{service: service}
+}
+
+// ListExchangeRates godoc
+// @Summary List exchange rates
+// @Tags exchange-rates
+// @Produce json
+// @Param base query string false "Base currency (ISO 4217)"
+// @Param quote query string false "Quote currency (ISO 4217)"
+// @Param from query string false "First rate date (YYYY-MM-DD)"
+// @Param to query string false "Last rate date (YYYY-MM-DD)"
+// @Param limit query int false "Limit (default 50, max 500)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/admin/exchange-rates [get]
+func (h *ExchangeRateHandler) List(c echo.Context) error {
+	from, err := parseOptionalDate(c.QueryParam("from"))
+	if err != nil {
+		return apperr.BadRequest("from must be in YYYY-MM-DD format")
+	}
+	to, err := parseOptionalDate(c.QueryParam("to"))
+	if err != nil {
+		return apperr.BadRequest("to must be in YYYY-MM-DD format")
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	rates, meta, err := h.service.List(c.QueryParam("base"), c.QueryParam("quote"), from, to, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": rates,
+		"meta": meta,
+	})
+}
+
+// UpsertExchangeRate godoc
+// @Summary Create or replace exchange rate
+// @Description Store the rate of one currency pair for a date, replacing an existing rate for the same date and pair
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Param rate body dtos.UpsertExchangeRateRequest true "Exchange rate"
+// @Success 200 {object} models.ExchangeRate
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/admin/exchange-rates [post]
+func (h *ExchangeRateHandler) Upsert(c echo.Context) error {
+	var req dtos.UpsertExchangeRateRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	rate, err := h.service.Upsert(req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, rate)
+}
+
+// UploadExchangeRates godoc
+// @Summary Upload exchange rates CSV
+// @Description Import rates from a CSV file with a date,base,quote,rate header. Existing rates for the same date and pair are replaced. Nothing is imported if any row is invalid.
+// @Tags exchange-rates
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV file"
+// @Success 200 {object} dtos.ImportResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/admin/exchange-rates/upload [post]
+func (h *ExchangeRateHandler) Upload(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return apperr.BadRequest("file is required")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+	defer file.Close()
+
+	imported, err := h.service.Import(file)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dtos.ImportResponse{Imported: imported})
+}
+
+// DeleteExchangeRate godoc
+// @Summary Delete exchange rate
+// @Tags exchange-rates
+// @Param id path string true "Exchange rate ID (UUID)"
+// @Success 204
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/admin/exchange-rates/{id} [delete]
+func (h *ExchangeRateHandler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
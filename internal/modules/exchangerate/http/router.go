@@ -0,0 +1,24 @@
+package http
+
+import (
+	"github.com/Ilmyrat1822/subs/cmd"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/handler"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/repository"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/service"
+)
+
+// InitExchangeRateRouter registers the admin endpoints and returns the
+// service so other modules can convert amounts with it.
+func InitExchangeRateRouter(server *cmd.Server) service.ExchangeRateService {
+	ratesRepository := repository.NewExchangeRateRepository(server.Database)
+	ratesService := service.NewExchangeRateService(ratesRepository)
+	ratesHandler := handler.NewExchangeRateHandler(ratesService)
+
+	ratesRouter := server.Echo.Group("/api/admin/exchange-rates")
+	ratesRouter.GET("", ratesHandler.List)
+	ratesRouter.POST("", ratesHandler.Upsert)
+	ratesRouter.POST("/upload", ratesHandler.Upload)
+	ratesRouter.DELETE("/:id", ratesHandler.Delete)
+
+	return ratesService
+}
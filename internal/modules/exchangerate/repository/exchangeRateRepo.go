@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+type ExchangeRateRepository interface {
+	List(base, quote string, from, to *time.Time, limit, offset int) ([]models.ExchangeRate, int64, error)
+	Upsert(rates []models.ExchangeRate) error
+	Delete(id uuid.UUID) (bool, error)
+	FindLatest(base, quote models.Currency, onOrBefore time.Time) (*models.ExchangeRate, error)
+}
+
+type exchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateRepository(db *gorm.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+func (r *exchangeRateRepository) List(base, quote string, from, to *time.Time, limit, offset int) ([]models.ExchangeRate, int64, error) {
+	var rates []models.ExchangeRate
+	var total int64
+
+	query := r.db.Model(&models.ExchangeRate{})
+
+	if base != "" {
+		query = query.Where("base_currency = ?", base)
+	}
+	if quote != "" {
+		query = query.Where("quote_currency = ?", quote)
+	}
+	if from != nil {
+		query = query.Where("rate_date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("rate_date <= ?", *to)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("rate_date DESC, base_currency, quote_currency").
+		Limit(limit).
+		Offset(offset).
+		Find(&rates).Error
+
+	return rates, total, err
+}
+
+// Upsert inserts rates, replacing the rate of any existing row for the same
+// date and currency pair.
+func (r *exchangeRateRepository) Upsert(rates []models.ExchangeRate) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "rate_date"},
+			{Name: "base_currency"},
+			{Name: "quote_currency"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&rates).Error
+}
+
+func (r *exchangeRateRepository) Delete(id uuid.UUID) (bool, error) {
+	res := r.db.Delete(&models.ExchangeRate{}, "id = ?", id)
+
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// FindLatest returns the most recent rate dated on or before onOrBefore for
+// the pair in either direction. A direct quote wins over an inverse one from
+// the same day.
+func (r *exchangeRateRepository) FindLatest(base, quote models.Currency, onOrBefore time.Time) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+
+	err := r.db.
+		Where(
+			"((base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?)) AND rate_date <= ?",
+			base, quote, quote, base, onOrBefore,
+		).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "rate_date DESC, (base_currency = ?) DESC",
+			Vars: []interface{}{base},
+		}}).
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &rate, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/repository"
+)
+
+type ExchangeRateService interface {
+	List(
+		base, quote string,
+		from, to *time.Time,
+		limit, offset int,
+	) ([]models.ExchangeRate, *dtos.PaginationMeta, error)
+	Upsert(req dtos.UpsertExchangeRateRequest) (*models.ExchangeRate, error)
+	Import(r io.Reader) (int, error)
+	Delete(id uuid.UUID) error
+	LookupRate(from, to models.Currency, month models.YearMonth) (*models.ExchangeRate, float64, error)
+}
+
+var (
+	ErrExchangeRateNotFound = apperr.New(apperr.NotFound, "exchange-rate-not-found", "exchange rate not found")
+	ErrInvalidExchangeRate  = apperr.New(apperr.Invalid, "invalid-exchange-rate", "invalid exchange rate")
+	ErrRateUnavailable      = apperr.New(apperr.Unprocessable, "exchange-rate-unavailable", "no exchange rate available")
+	ErrInvalidImport        = apperr.New(apperr.Invalid, "invalid-import", "invalid CSV rows, nothing was imported")
+)
+
+// ImportError lists the CSV lines that could not be parsed. Imports are all
+// or nothing, so nothing is stored when it is returned.
+type ImportError struct {
+	Lines []dtos.ImportLineError
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("%d invalid CSV row(s), nothing was imported", len(e.Lines))
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrInvalidImport
+}
+
+// FieldErrors lists the invalid lines for the problem response.
+func (e *ImportError) FieldErrors() []apperr.FieldError {
+	fields := make([]apperr.FieldError, len(e.Lines))
+	for i, line := range e.Lines {
+		fields[i] = apperr.FieldError{Line: line.Line, Message: line.Error}
+	}
+	return fields
+}
+
+type exchangeRateService struct {
+	repo repository.ExchangeRateRepository
+}
+
+func NewExchangeRateService(
+	repo repository.ExchangeRateRepository,
+) ExchangeRateService {
+	return &exchangeRateService{repo: repo}
+}
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+func (s *exchangeRateService) List(base, quote string, from, to *time.Time, limit, offset int) ([]models.ExchangeRate, *dtos.PaginationMeta, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rates, total, err := s.repo.List(base, quote, from, to, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := &dtos.PaginationMeta{
+		Limit:  limit,
+		Offset: offset,
+		Total:  int(total),
+	}
+
+	return rates, meta, nil
+}
+
+func (s *exchangeRateService) Upsert(req dtos.UpsertExchangeRateRequest) (*models.ExchangeRate, error) {
+	rate, err := newExchangeRate(req.Date, string(req.Base), string(req.Quote), req.Rate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Upsert([]models.ExchangeRate{*rate}); err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+// Import reads rates from CSV with a header naming the date, base, quote and
+// rate columns, in any order. Every row is validated before anything is
+// written.
+func (s *exchangeRateService) Import(r io.Reader) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%w: cannot read CSV header: %v", ErrInvalidExchangeRate, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return 0, fmt.Errorf("%w: CSV header must contain date, base, quote and rate", ErrInvalidExchangeRate)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	importErr := &ImportError{}
+	// seen maps a date and pair to the line that set it, since a second
+	// rate for the same key cannot be stored by one upsert.
+	seen := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			importErr.Lines = append(importErr.Lines, dtos.ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		value, parseErr := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if parseErr != nil {
+			importErr.Lines = append(importErr.Lines, dtos.ImportLineError{
+				Line:  line,
+				Error: fmt.Sprintf("invalid rate %q", record[columns["rate"]]),
+			})
+			continue
+		}
+
+		rate, err := newExchangeRate(record[columns["date"]], record[columns["base"]], record[columns["quote"]], value)
+		if err != nil {
+			importErr.Lines = append(importErr.Lines, dtos.ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+		key := fmt.Sprintf("%s %s %s", rate.RateDate.Format(time.DateOnly), rate.BaseCurrency, rate.QuoteCurrency)
+		if first, ok := seen[key]; ok {
+			importErr.Lines = append(importErr.Lines, dtos.ImportLineError{
+				Line:  line,
+				Error: fmt.Sprintf("duplicate of line %d", first),
+			})
+			continue
+		}
+		seen[key] = line
+		rates = append(rates, *rate)
+	}
+
+	if len(importErr.Lines) > 0 {
+		return 0, importErr
+	}
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	if err := s.repo.Upsert(rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (s *exchangeRateService) Delete(id uuid.UUID) error {
+	found, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrExchangeRateNotFound
+	}
+	return nil
+}
+
+// LookupRate finds the rate for converting from into to during month: the
+// latest quote dated on or before the last day of the month. It returns the
+// stored row and the multiplier to apply, which is the inverse of the stored
+// rate when only the opposite pair is known. Same-currency lookups return a
+// nil row and a multiplier of 1.
+func (s *exchangeRateService) LookupRate(from, to models.Currency, month models.YearMonth) (*models.ExchangeRate, float64, error) {
+	if from == to {
+		return nil, 1, nil
+	}
+
+	rate, err := s.repo.FindLatest(from, to, month.LastDay())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, fmt.Errorf("%w: %s to %s for %s", ErrRateUnavailable, from, to, month)
+		}
+		return nil, 0, err
+	}
+
+	if rate.BaseCurrency == from {
+		return rate, rate.Rate, nil
+	}
+	return rate, 1 / rate.Rate, nil
+}
+
+func newExchangeRate(date, base, quote string, rate float64) (*models.ExchangeRate, error) {
+	rateDate, err := time.Parse(time.DateOnly, strings.TrimSpace(date))
+	if err != nil {
+		return nil, fmt.Errorf("%w: date must be in YYYY-MM-DD format", ErrInvalidExchangeRate)
+	}
+	baseCurrency, err := models.ParseCurrency(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base: %v", ErrInvalidExchangeRate, err)
+	}
+	quoteCurrency, err := models.ParseCurrency(quote)
+	if err != nil {
+		return nil, fmt.Errorf("%w: quote: %v", ErrInvalidExchangeRate, err)
+	}
+	if baseCurrency == quoteCurrency {
+		return nil, fmt.Errorf("%w: base and quote must differ", ErrInvalidExchangeRate)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("%w: rate must be positive", ErrInvalidExchangeRate)
+	}
+
+	return &models.ExchangeRate{
+		RateDate:      rateDate,
+		BaseCurrency:  baseCurrency,
+		QuoteCurrency: quoteCurrency,
+		Rate:          rate,
+	}, nil
+}
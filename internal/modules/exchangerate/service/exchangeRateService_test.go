@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/exchangerate/repository"
+)
+
+// memoryRates keeps rates in memory. FindLatest follows the query of the
+// real repository: the latest rate on or before the date in either
+// direction, a direct quote winning over an inverse one of the same day.
+type memoryRates struct {
+	repository.ExchangeRateRepository
+	rates    []models.ExchangeRate
+	upserted []models.ExchangeRate
+}
+
+func (r *memoryRates) FindLatest(base, quote models.Currency, onOrBefore time.Time) (*models.ExchangeRate, error) {
+	var best *models.ExchangeRate
+	for i, rate := range r.rates {
+		direct := rate.BaseCurrency == base && rate.QuoteCurrency == quote
+		inverse := rate.BaseCurrency == quote && rate.QuoteCurrency == base
+		if (!direct && !inverse) || rate.RateDate.After(onOrBefore) {
+			continue
+		}
+		if best == nil || rate.RateDate.After(best.RateDate) || (rate.RateDate.Equal(best.RateDate) && direct) {
+			best = &r.rates[i]
+		}
+	}
+	if best == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return best, nil
+}
+
+func (r *memoryRates) Upsert(rates []models.ExchangeRate) error {
+	r.upserted = append(r.upserted, rates...)
+	return nil
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestLookupRate(t *testing.T) {
+	repo := &memoryRates{rates: []models.ExchangeRate{
+		{RateDate: day(2025, time.June, 2), BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 80},
+		{RateDate: day(2025, time.July, 1), BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 90},
+		{RateDate: day(2025, time.July, 31), BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 92},
+		{RateDate: day(2025, time.August, 1), BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 95},
+		{RateDate: day(2025, time.July, 15), BaseCurrency: "RUB", QuoteCurrency: "EUR", Rate: 0.01},
+	}}
+	svc := NewExchangeRateService(repo)
+
+	tests := []struct {
+		name     string
+		from, to models.Currency
+		month    models.YearMonth
+		wantDate time.Time
+		want     float64
+		wantErr  error
+	}{
+		{name: "last day of the month counts", from: "USD", to: "RUB", month: models.NewYearMonth(2025, time.July), wantDate: day(2025, time.July, 31), want: 92},
+		{name: "earlier month", from: "USD", to: "RUB", month: models.NewYearMonth(2025, time.June), wantDate: day(2025, time.June, 2), want: 80},
+		{name: "falls back to an older rate", from: "USD", to: "RUB", month: models.NewYearMonth(2025, time.December), wantDate: day(2025, time.August, 1), want: 95},
+		{name: "inverse pair", from: "RUB", to: "USD", month: models.NewYearMonth(2025, time.June), wantDate: day(2025, time.June, 2), want: 1.0 / 80},
+		{name: "stored as given", from: "RUB", to: "EUR", month: models.NewYearMonth(2025, time.July), wantDate: day(2025, time.July, 15), want: 0.01},
+		{name: "same currency", from: "RUB", to: "RUB", month: models.NewYearMonth(2025, time.July), want: 1},
+		{name: "before the first rate", from: "USD", to: "RUB", month: models.NewYearMonth(2025, time.May), wantErr: ErrRateUnavailable},
+		{name: "unknown pair", from: "USD", to: "EUR", month: models.NewYearMonth(2025, time.July), wantErr: ErrRateUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, multiplier, err := svc.LookupRate(tt.from, tt.to, tt.month)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LookupRate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LookupRate() error = %v", err)
+			}
+			if multiplier != tt.want {
+				t.Errorf("multiplier = %v, want %v", multiplier, tt.want)
+			}
+			var gotDate time.Time
+			if rate != nil {
+				gotDate = rate.RateDate
+			}
+			if !gotDate.Equal(tt.wantDate) {
+				t.Errorf("rate dated %v, want %v", gotDate, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		csv       string
+		want      int
+		wantLines []dtos.ImportLineError
+		wantErr   error
+	}{
+		{
+			name: "columns in any order",
+			csv:  "rate,quote,base,date\n92.5,rub,usd,2025-07-01\n0.011,EUR, RUB ,2025-07-01\n",
+			want: 2,
+		},
+		{
+			name: "header only",
+			csv:  "date,base,quote,rate\n",
+			want: 0,
+		},
+		{
+			name:    "missing column",
+			csv:     "date,base,rate\n2025-07-01,USD,92.5\n",
+			wantErr: ErrInvalidExchangeRate,
+		},
+		{
+			name:    "empty file",
+			csv:     "",
+			wantErr: ErrInvalidExchangeRate,
+		},
+		{
+			name: "bad rows",
+			csv: "date,base,quote,rate\n" +
+				"2025-07-01,USD,RUB,92.5\n" +
+				"07-2025,USD,RUB,92.5\n" +
+				"2025-07-01,USD,XXX,92.5\n" +
+				"2025-07-01,USD,RUB,abc\n" +
+				"2025-07-01,USD,USD,1\n" +
+				"2025-07-01,EUR,RUB,-1\n" +
+				"2025-07-01,EUR\n",
+			wantLines: []dtos.ImportLineError{
+				{Line: 3, Error: "date must be in YYYY-MM-DD format"},
+				{Line: 4, Error: "quote"},
+				{Line: 5, Error: `invalid rate "abc"`},
+				{Line: 6, Error: "base and quote must differ"},
+				{Line: 7, Error: "rate must be positive"},
+				{Line: 8, Error: "wrong number of fields"},
+			},
+		},
+		{
+			name: "duplicate dates",
+			csv: "date,base,quote,rate\n" +
+				"2025-07-01,USD,RUB,92.5\n" +
+				"2025-07-01,RUB,USD,0.011\n" +
+				"2025-07-01,usd,rub,93\n",
+			wantLines: []dtos.ImportLineError{
+				{Line: 4, Error: "duplicate of line 2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryRates{}
+			got, err := NewExchangeRateService(repo).Import(strings.NewReader(tt.csv))
+
+			if tt.wantLines != nil {
+				var importErr *ImportError
+				if !errors.As(err, &importErr) {
+					t.Fatalf("Import() error = %v, want an ImportError", err)
+				}
+				if len(importErr.Lines) != len(tt.wantLines) {
+					t.Fatalf("Import() lines = %+v, want %+v", importErr.Lines, tt.wantLines)
+				}
+				for i, line := range importErr.Lines {
+					want := tt.wantLines[i]
+					if line.Line != want.Line || !strings.Contains(line.Error, want.Error) {
+						t.Errorf("line error %d = %+v, want line %d containing %q", i, line, want.Line, want.Error)
+					}
+				}
+				if len(repo.upserted) != 0 {
+					t.Errorf("stored %d rates from an invalid file", len(repo.upserted))
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Import() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if got != tt.want || len(repo.upserted) != tt.want {
+				t.Errorf("Import() = %d with %d stored, want %d", got, len(repo.upserted), tt.want)
+			}
+		})
+	}
+}
+
+func TestImportNormalizesRows(t *testing.T) {
+	repo := &memoryRates{}
+	csv := "date,base,quote,rate\n 2025-07-01 , usd ,rub, 92.5 \n"
+	if _, err := NewExchangeRateService(repo).Import(strings.NewReader(csv)); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	want := []models.ExchangeRate{{RateDate: day(2025, time.July, 1), BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 92.5}}
+	if !reflect.DeepEqual(repo.upserted, want) {
+		t.Errorf("stored %+v, want %+v", repo.upserted, want)
+	}
+}
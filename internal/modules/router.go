@@ -1,10 +1,41 @@
 package internal
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"github.com/Ilmyrat1822/subs/cmd"
+	"github.com/Ilmyrat1822/subs/internal/deprecation"
+	"github.com/Ilmyrat1822/subs/internal/idempotency"
+	budgetRouter "github.com/Ilmyrat1822/subs/internal/modules/budget/http"
+	ratesRouter "github.com/Ilmyrat1822/subs/internal/modules/exchangerate/http"
 	subsRouter "github.com/Ilmyrat1822/subs/internal/modules/subscription/http"
 )
 
 func InitRouters(server *cmd.Server) {
-	subsRouter.InitSubscriptionRouter(server)
+	idempotencyStore := idempotency.NewStore(server.Database, server.Config.IdempotencyKeyTTL)
+	server.Jobs.Every("idempotency-cleanup", server.Config.IdempotencyCleanup, func(ctx context.Context) error {
+		_, err := idempotencyStore.DeleteExpired()
+		return err
+	})
+
+	// Every module deprecates its unversioned routes on the same dates.
+	legacy := deprecation.Window{
+		DeprecatedAt: legacyDate("LEGACY_API_DEPRECATED_AT", server.Config.LegacyAPIDeprecatedAt),
+		Sunset:       legacyDate("LEGACY_API_SUNSET", server.Config.LegacyAPISunset),
+	}
+
+	ratesService := ratesRouter.InitExchangeRateRouter(server)
+	subsService := subsRouter.InitSubscriptionRouter(server, ratesService, idempotency.Middleware(idempotencyStore), legacy)
+	budgetService := budgetRouter.InitBudgetRouter(server, subsService, legacy)
+	subsService.ObserveSpend(budgetService)
+}
+
+func legacyDate(name, value string) time.Time {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		log.Fatalf("invalid %s, expected YYYY-MM-DD: %v", name, err)
+	}
+	return date
 }
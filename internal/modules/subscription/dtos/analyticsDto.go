@@ -0,0 +1,114 @@
+package dtos
+
+import "strings"
+
+// AnalyticsPeriod is the inclusive month range of an analytics report, read
+// from the query string.
+type AnalyticsPeriod struct {
+	From string `query:"from" validate:"required,mmyyyy"`
+	To   string `query:"to" validate:"required,mmyyyy,monthgtefield=From"`
+}
+
+// MonthlySpendingResponse is a spending time series with one entry per
+// calendar month, amounts in minor units of Currency.
+type MonthlySpendingResponse struct {
+	Currency  string          `json:"currency" example:"RUB"`
+	Months    []MonthSpending `json:"months"`
+	RatesUsed []RateUsed      `json:"rates_used,omitempty"`
+}
+
+// MonthSpending is one month of a spending time series. Total is the sum of
+// the charges due in the month. Active counts the subscriptions running and
+// not paused in it, New those starting and Ended those ending in it.
+type MonthSpending struct {
+	Month      string           `json:"month" example:"07-2025"`
+	Total      int64            `json:"total" example:"129800"`
+	ByCurrency map[string]int64 `json:"by_currency,omitempty"`
+	Active     int64            `json:"active" example:"4"`
+	New        int64            `json:"new" example:"1"`
+	Ended      int64            `json:"ended" example:"0"`
+}
+
+// Groupings of a spending breakdown.
+const (
+	GroupByServiceName = "service_name"
+	GroupByCategory    = "category"
+	GroupByUserID      = "user_id"
+	GroupByMonth       = "month"
+)
+
+// BreakdownQuery selects a spending breakdown. With Top set, only the Top
+// most expensive groups are listed and the rest are summed into one.
+type BreakdownQuery struct {
+	From    string `query:"from" validate:"required,mmyyyy"`
+	To      string `query:"to" validate:"required,mmyyyy,monthgtefield=From"`
+	GroupBy string `query:"group_by" validate:"required,oneof=service_name category user_id month"`
+	Top     int    `query:"top" validate:"gte=0,max=100"`
+}
+
+// SpendingBreakdownResponse splits the total spending of a period into
+// groups, amounts in minor units of Currency.
+type SpendingBreakdownResponse struct {
+	GroupBy   string          `json:"group_by" example:"service_name"`
+	Currency  string          `json:"currency" example:"RUB"`
+	Total     int64           `json:"total" example:"478800"`
+	Groups    []SpendingGroup `json:"groups"`
+	RatesUsed []RateUsed      `json:"rates_used,omitempty"`
+}
+
+// SpendingGroup is the spending of one service, category, user or month.
+// Share is its percentage of the total, the shares of all groups adding up
+// to 100, and Count the number of subscriptions charged in it. Other marks
+// the group that sums up everything beyond the top groups.
+type SpendingGroup struct {
+	Key   string  `json:"key" example:"Yandex Plus"`
+	Cost  int64   `json:"cost" example:"239400"`
+	Share float64 `json:"share" example:"50"`
+	Count int     `json:"count" example:"1"`
+	Other bool    `json:"other,omitempty"`
+}
+
+// ForecastQuery selects a forecast of the next Months months, 12 when
+// omitted.
+type ForecastQuery struct {
+	Months int `query:"months" validate:"omitempty,min=1,max=60"`
+}
+
+// ChurnAssumptions are the monthly probabilities that a subscription is
+// cancelled. ByService is keyed by lower-cased service name and overrides
+// Default.
+type ChurnAssumptions struct {
+	Default   float64
+	ByService map[string]float64
+}
+
+// For returns the churn probability of the service named serviceName.
+func (a *ChurnAssumptions) For(serviceName string) float64 {
+	if p, ok := a.ByService[strings.ToLower(serviceName)]; ok {
+		return p
+	}
+	return a.Default
+}
+
+// ForecastResponse is the expected spending of the coming months, amounts
+// in minor units of Currency. Confidence is the probability covered by the
+// low and high bounds of every month, which are only given when churn
+// assumptions were supplied.
+type ForecastResponse struct {
+	Currency   string          `json:"currency" example:"RUB"`
+	Months     []ForecastMonth `json:"months"`
+	Total      int64           `json:"total" example:"1436400"`
+	Confidence float64         `json:"confidence,omitempty" example:"0.9"`
+	RatesUsed  []RateUsed      `json:"rates_used,omitempty"`
+}
+
+// ForecastMonth is one month of a forecast. Committed is what the existing
+// subscriptions will cost if none is cancelled early, with their end dates
+// and scheduled prices; Expected discounts it by the assumed churn.
+type ForecastMonth struct {
+	Month     string `json:"month" example:"07-2025"`
+	Expected  int64  `json:"expected" example:"119700"`
+	Committed int64  `json:"committed" example:"129800"`
+	Low       *int64 `json:"low,omitempty" example:"99800"`
+	High      *int64 `json:"high,omitempty" example:"129800"`
+}
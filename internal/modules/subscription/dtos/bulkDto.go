@@ -0,0 +1,65 @@
+package dtos
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// Bulk modes of BulkRequest.Mode.
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best_effort"
+)
+
+// Bulk operations of BulkOperation.Op.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkRequest applies several operations at once. In atomic mode, the
+// default, either all of them are applied or none; in best_effort mode each
+// one succeeds or fails on its own.
+type BulkRequest struct {
+	Mode       string          `json:"mode,omitempty" example:"atomic"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is one create, update or delete. Data holds a
+// CreateSubscriptionRequest or UpdateSubscriptionRequest; ID and the
+// optional Version, which works like If-Match, select the subscription to
+// update or delete.
+type BulkOperation struct {
+	Op      string          `json:"op" example:"create"`
+	ID      *uuid.UUID      `json:"id,omitempty" example:"2f1c3f1e-8c5b-4d6e-9f7a-0b1c2d3e4f50"`
+	Version *int64          `json:"version,omitempty" example:"3"`
+	Data    json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+type BulkResponse struct {
+	Mode      string           `json:"mode" example:"atomic"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded" example:"2"`
+	Failed    int              `json:"failed" example:"0"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// BulkItemResult reports the outcome of the operation at Index with the
+// status code the single-item endpoint would have answered. Errors are the
+// field errors of a failed operation, as in a problem response. Warnings
+// name the likely duplicates of a create when the duplicate guard warns.
+type BulkItemResult struct {
+	Index        int                  `json:"index" example:"0"`
+	Op           string               `json:"op" example:"create"`
+	ID           *uuid.UUID           `json:"id,omitempty"`
+	Status       int                  `json:"status" example:"201"`
+	Error        string               `json:"error,omitempty"`
+	Errors       []apperr.FieldError  `json:"errors,omitempty"`
+	Warnings     []string             `json:"warnings,omitempty"`
+	Subscription *models.Subscription `json:"subscription,omitempty"`
+}
@@ -0,0 +1,29 @@
+package dtos
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// Modes of the duplicate guard on subscription creation.
+const (
+	DuplicateGuardOff    = "off"
+	DuplicateGuardWarn   = "warn"
+	DuplicateGuardReject = "reject"
+)
+
+// DuplicatesResponse lists the likely duplicate subscriptions found.
+type DuplicatesResponse struct {
+	Count  int              `json:"count" example:"1"`
+	Groups []DuplicateGroup `json:"groups"`
+}
+
+// DuplicateGroup is a set of subscriptions of one user whose service names
+// are equal once normalized and whose periods overlap, earliest first.
+// ServiceKey is the normalized name.
+type DuplicateGroup struct {
+	UserID        uuid.UUID             `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceKey    string                `json:"service_key" example:"yandexplus"`
+	Subscriptions []models.Subscription `json:"subscriptions"`
+}
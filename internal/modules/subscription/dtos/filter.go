@@ -0,0 +1,72 @@
+package dtos
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// Name match modes of SubscriptionFilter.NameMatch.
+const (
+	NameMatchContains = "contains"
+	NameMatchExact    = "exact"
+)
+
+// SubscriptionFilter selects subscriptions. It is shared by the list and
+// total endpoints so both agree on which rows match; zero fields do not
+// filter.
+type SubscriptionFilter struct {
+	UserID      string
+	ServiceName string
+	// NameMatch is NameMatchContains (substring, the default) or
+	// NameMatchExact; both ignore case.
+	NameMatch string
+	// Category matches case-insensitively.
+	Category   string
+	Status     string
+	PriceMin   *int64
+	PriceMax   *int64
+	ActiveAt   *models.YearMonth
+	StartFrom  *models.YearMonth
+	StartTo    *models.YearMonth
+	HasEndDate *bool
+	Sort       []SortField
+}
+
+// SortField orders results by a whitelisted field, descending when Desc.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// sortableFields whitelists the fields accepted by ParseSort.
+var sortableFields = map[string]bool{
+	"service_name": true,
+	"price":        true,
+	"currency":     true,
+	"status":       true,
+	"start_date":   true,
+	"end_date":     true,
+	"created_at":   true,
+	"updated_at":   true,
+}
+
+// ParseSort parses a comma-separated list of fields such as
+// "price,-start_date", where a leading minus sorts descending.
+func ParseSort(value string) ([]SortField, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !sortableFields[field.Field] {
+			return nil, fmt.Errorf("cannot sort by %q", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -1,7 +1,12 @@
 package dtos
 
-type PaginationMeta struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+// PageRequest selects a page of a listing. A non-empty Cursor, taken from
+// the next or prev field of a previous response, takes precedence over
+// Offset. IncludeTotal defaults to true in offset mode and false with a
+// cursor.
+type PageRequest struct {
+	Limit        int
+	Offset       int
+	Cursor       string
+	IncludeTotal *bool
 }
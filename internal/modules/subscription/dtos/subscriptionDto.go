@@ -1,18 +1,100 @@
 package dtos
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
 
 type CreateSubscriptionRequest struct {
-	ServiceName string    `json:"service_name" binding:"required" example:"Yandex Plus"`
-	Price       int       `json:"price" binding:"required,min=0" example:"400"`
-	UserID      uuid.UUID `json:"user_id" binding:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
-	StartDate   string    `json:"start_date" binding:"required" example:"07-2025"`
-	EndDate     *string   `json:"end_date,omitempty" example:"12-2025"`
+	ServiceName   string               `json:"service_name" validate:"required,servicename" example:"Yandex Plus"`
+	Category      *string              `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Price         int64                `json:"price" validate:"gte=0" example:"39900"`
+	Currency      models.Currency      `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	BillingPeriod models.BillingPeriod `json:"billing_period,omitempty" swaggertype:"string" example:"monthly"`
+	UserID        uuid.UUID            `json:"user_id" validate:"required" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     models.YearMonth     `json:"start_date" validate:"required,mmyyyy" swaggertype:"string" example:"07-2025"`
+	EndDate       *models.YearMonth    `json:"end_date,omitempty" validate:"omitempty,mmyyyy,monthgtefield=StartDate" swaggertype:"string" example:"12-2025"`
+	TrialEnd      *models.YearMonth    `json:"trial_end,omitempty" validate:"omitempty,mmyyyy,monthgtefield=StartDate,monthltefield=EndDate" swaggertype:"string" example:"08-2025"`
 }
 
+// UpdateSubscriptionRequest changes the fields that are present. An empty
+// category removes it.
 type UpdateSubscriptionRequest struct {
-	ServiceName *string `json:"service_name,omitempty" example:"Yandex Plus"`
-	Price       *int    `json:"price,omitempty" example:"400"`
-	StartDate   *string `json:"start_date,omitempty" example:"07-2025"`
-	EndDate     *string `json:"end_date,omitempty" example:"12-2025"`
+	ServiceName        *string               `json:"service_name,omitempty" validate:"omitempty,servicename" example:"Yandex Plus"`
+	Category           *string               `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Price              *int64                `json:"price,omitempty" validate:"omitempty,gte=0" example:"39900"`
+	PriceEffectiveFrom *models.YearMonth     `json:"price_effective_from,omitempty" validate:"omitempty,mmyyyy" swaggertype:"string" example:"01-2026"`
+	Currency           *models.Currency      `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	BillingPeriod      *models.BillingPeriod `json:"billing_period,omitempty" swaggertype:"string" example:"yearly"`
+	StartDate          *models.YearMonth     `json:"start_date,omitempty" validate:"omitempty,mmyyyy" swaggertype:"string" example:"07-2025"`
+	EndDate            *models.YearMonth     `json:"end_date,omitempty" validate:"omitempty,mmyyyy,monthgtefield=StartDate" swaggertype:"string" example:"12-2025"`
+	TrialEnd           *models.YearMonth     `json:"trial_end,omitempty" validate:"omitempty,mmyyyy,monthgtefield=StartDate,monthltefield=EndDate" swaggertype:"string" example:"08-2025"`
+}
+
+// SubscriptionDocument is the editable part of a subscription that PATCH
+// requests are applied to. Price is the price in force this month; changing
+// it schedules the new price from this month on.
+type SubscriptionDocument struct {
+	ServiceName   string               `json:"service_name" validate:"required,servicename" example:"Yandex Plus"`
+	Category      *string              `json:"category" validate:"omitempty,max=64" example:"streaming"`
+	Price         int64                `json:"price" validate:"gte=0" example:"39900"`
+	Currency      models.Currency      `json:"currency" validate:"required" swaggertype:"string" example:"RUB"`
+	BillingPeriod models.BillingPeriod `json:"billing_period" validate:"required" swaggertype:"string" example:"monthly"`
+	StartDate     models.YearMonth     `json:"start_date" validate:"required,mmyyyy" swaggertype:"string" example:"07-2025"`
+	EndDate       *models.YearMonth    `json:"end_date" validate:"omitempty,mmyyyy,monthgtefield=StartDate" swaggertype:"string" example:"12-2025"`
+	TrialEnd      *models.YearMonth    `json:"trial_end" validate:"omitempty,mmyyyy,monthgtefield=StartDate,monthltefield=EndDate" swaggertype:"string" example:"08-2025"`
+}
+
+// SubscriptionListItem is a subscription as returned by the list endpoint,
+// with its price normalized to a monthly cost, in minor units of its
+// currency, for comparison.
+type SubscriptionListItem struct {
+	models.Subscription
+	MonthlyCost int64 `example:"39900"`
+}
+
+// TrashItem is a deleted subscription, which is permanently removed at
+// PurgeAt unless it is restored first.
+type TrashItem struct {
+	models.Subscription
+	PurgeAt time.Time
+}
+
+// PurgeResponse reports how many subscriptions a purge removed.
+type PurgeResponse struct {
+	Purged int64 `json:"purged" example:"3"`
+}
+
+// TrialEndingItem is a subscription whose trial converts to paid on
+// ConvertsOn, DaysLeft days from today.
+type TrialEndingItem struct {
+	models.Subscription
+	ConvertsOn string `example:"2025-09-01"`
+	DaysLeft   int    `example:"12"`
+}
+
+// StatusChangeRequest is the optional body of the lifecycle actions. The
+// change takes effect from the current month unless Effective is given.
+type StatusChangeRequest struct {
+	Effective *models.YearMonth `json:"effective,omitempty" validate:"omitempty,mmyyyy" swaggertype:"string" example:"08-2025"`
+	Reason    string            `json:"reason,omitempty" validate:"max=500" example:"Travelling for two months"`
+}
+
+// SchedulePriceRequest changes the price from EffectiveFrom, the current
+// month when omitted. Earlier months keep the price they had.
+type SchedulePriceRequest struct {
+	Price         int64             `json:"price" validate:"gte=0" example:"44900"`
+	EffectiveFrom *models.YearMonth `json:"effective_from,omitempty" validate:"omitempty,mmyyyy" swaggertype:"string" example:"01-2026"`
+}
+
+type PriceTimelineEntry struct {
+	Price         int64   `json:"price" example:"44900"`
+	Currency      string  `json:"currency" example:"RUB"`
+	EffectiveFrom string  `json:"effective_from" example:"01-2026"`
+	EffectiveTo   *string `json:"effective_to,omitempty" example:"06-2026"`
+	Current       bool    `json:"current"`
+	Scheduled     bool    `json:"scheduled"`
 }
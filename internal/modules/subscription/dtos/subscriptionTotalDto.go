@@ -1,10 +1,54 @@
 package dtos
 
+import "github.com/google/uuid"
+
+// TotalCostPeriod is the period of the total cost endpoint, read from the
+// query string.
+type TotalCostPeriod struct {
+	StartDate string `query:"start_date" validate:"required,mmyyyy"`
+	EndDate   string `query:"end_date" validate:"required,mmyyyy,monthgtefield=StartDate"`
+}
+
+// TotalCostResponse amounts are in minor units of Currency. ByCurrency holds
+// the same charges summed in their original currencies.
 type TotalCostResponse struct {
-	Total int64
-	Count int64
+	Total      int64
+	Count      int64
+	Currency   string              `json:"currency" example:"RUB"`
+	ByCurrency map[string]int64    `json:"by_currency,omitempty"`
+	RatesUsed  []RateUsed          `json:"rates_used,omitempty"`
+	Breakdown  *TotalCostBreakdown `json:"breakdown,omitempty"`
+}
+
+// RateUsed records the exchange rate applied to charges of one month.
+type RateUsed struct {
+	Month    string  `json:"month" example:"07-2025"`
+	From     string  `json:"from" example:"USD"`
+	To       string  `json:"to" example:"RUB"`
+	Rate     float64 `json:"rate" example:"92.5"`
+	RateDate string  `json:"rate_date" example:"2025-07-01"`
+}
+
+// TotalCostBreakdown splits a total into the amounts charged per calendar
+// month and per subscription, so the figure can be reconciled line by line.
+type TotalCostBreakdown struct {
+	ByMonth        []MonthCost        `json:"by_month"`
+	BySubscription []SubscriptionCost `json:"by_subscription"`
+}
+
+type MonthCost struct {
+	Month  string `json:"month" example:"07-2025"`
+	Amount int64  `json:"amount" example:"39900"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error" example:"Invalid request"`
+type SubscriptionCost struct {
+	SubscriptionID uuid.UUID   `json:"subscription_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName    string      `json:"service_name" example:"Yandex Plus"`
+	Price          int64       `json:"price" example:"39900"`
+	Currency       string      `json:"currency" example:"RUB"`
+	BillingPeriod  string      `json:"billing_period" example:"monthly"`
+	Charges        int         `json:"charges" example:"6"`
+	Months         int         `json:"months" example:"6"`
+	Total          int64       `json:"total" example:"239400"`
+	ByMonth        []MonthCost `json:"by_month"`
 }
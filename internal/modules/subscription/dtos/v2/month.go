@@ -0,0 +1,82 @@
+// Package v2 holds the request and response bodies of API v2. They are
+// mapped onto the types of package dtos, so both versions share one service.
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// Month is a calendar month exchanged as ISO 8601 YYYY-MM, where v1 uses
+// MM-YYYY. The zero value is "no month" and is written as null.
+type Month models.YearMonth
+
+func MonthOf(month models.YearMonth) Month {
+	return Month(month)
+}
+
+func (m Month) YearMonth() models.YearMonth {
+	return models.YearMonth(m)
+}
+
+func (m Month) String() string {
+	return m.YearMonth().ISOString()
+}
+
+func (m Month) MarshalJSON() ([]byte, error) {
+	if m.YearMonth().IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(m.String())
+}
+
+func (m *Month) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %s", models.ErrInvalidISOYearMonth, data)
+	}
+	return m.UnmarshalText([]byte(value))
+}
+
+func (m Month) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Month) UnmarshalText(text []byte) error {
+	month, err := models.ParseISOYearMonth(string(text))
+	if err != nil {
+		return err
+	}
+	*m = Month(month)
+	return nil
+}
+
+func monthPtr(month *models.YearMonth) *Month {
+	if month == nil {
+		return nil
+	}
+	m := Month(*month)
+	return &m
+}
+
+func yearMonthPtr(month *Month) *models.YearMonth {
+	if month == nil {
+		return nil
+	}
+	ym := month.YearMonth()
+	return &ym
+}
+
+// isoMonth rewrites a MM-YYYY month produced by the service as YYYY-MM.
+func isoMonth(value string) string {
+	month, err := models.ParseYearMonth(value)
+	if err != nil {
+		return value
+	}
+	return month.ISOString()
+}
@@ -0,0 +1,152 @@
+package v2
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
+)
+
+// Subscription is a subscription as returned by API v2: snake_case fields,
+// months as YYYY-MM and timestamps in RFC 3339. Price is in minor units of
+// Currency.
+type Subscription struct {
+	ID               uuid.UUID  `json:"id" format:"uuid" example:"3f0c1a52-8a36-4f55-9a55-2c3c1d6f8e21"`
+	ServiceName      string     `json:"service_name" example:"Yandex Plus"`
+	Category         *string    `json:"category" example:"streaming"`
+	Price            int64      `json:"price" example:"39900"`
+	Currency         string     `json:"currency" example:"RUB"`
+	BillingPeriod    string     `json:"billing_period" example:"monthly"`
+	UserID           uuid.UUID  `json:"user_id" format:"uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate        Month      `json:"start_date" swaggertype:"string" example:"2025-07"`
+	EndDate          *Month     `json:"end_date" swaggertype:"string" example:"2025-12"`
+	TrialEnd         *Month     `json:"trial_end" swaggertype:"string" example:"2025-08"`
+	TrialConvertedAt *time.Time `json:"trial_converted_at"`
+	Status           string     `json:"status" example:"active"`
+	Pauses           []Pause    `json:"pauses"`
+	Version          int64      `json:"version" example:"1"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// Pause is a period in which a subscription was not charged. ResumedFrom is
+// null while it is still paused.
+type Pause struct {
+	PausedFrom  Month  `json:"paused_from" swaggertype:"string" example:"2025-08"`
+	ResumedFrom *Month `json:"resumed_from" swaggertype:"string" example:"2025-10"`
+}
+
+func NewSubscription(sub *models.Subscription) Subscription {
+	pauses := make([]Pause, len(sub.Pauses))
+	for i, pause := range sub.Pauses {
+		pauses[i] = Pause{PausedFrom: MonthOf(pause.PausedFrom), ResumedFrom: monthPtr(pause.ResumedFrom)}
+	}
+	return Subscription{
+		ID:               sub.ID,
+		ServiceName:      sub.ServiceName,
+		Category:         sub.Category,
+		Price:            sub.Price,
+		Currency:         string(sub.Currency),
+		BillingPeriod:    string(sub.BillingPeriod),
+		UserID:           sub.UserID,
+		StartDate:        MonthOf(sub.StartDate),
+		EndDate:          monthPtr(sub.EndDate),
+		TrialEnd:         monthPtr(sub.TrialEnd),
+		TrialConvertedAt: sub.TrialConvertedAt,
+		Status:           string(sub.Status),
+		Pauses:           pauses,
+		Version:          sub.Version,
+		CreatedAt:        sub.CreatedAt,
+		UpdatedAt:        sub.UpdatedAt,
+	}
+}
+
+// SubscriptionListItem adds the price normalized to a monthly cost, in minor
+// units of the subscription's currency.
+type SubscriptionListItem struct {
+	Subscription
+	MonthlyCost int64 `json:"monthly_cost" example:"39900"`
+}
+
+// SubscriptionList is a page of subscriptions.
+type SubscriptionList struct {
+	Data []SubscriptionListItem `json:"data"`
+	Meta *pagination.Meta       `json:"meta"`
+}
+
+func NewSubscriptionList(items []dtos.SubscriptionListItem, meta *pagination.Meta) SubscriptionList {
+	list := SubscriptionList{Data: make([]SubscriptionListItem, len(items)), Meta: meta}
+	for i, item := range items {
+		list.Data[i] = SubscriptionListItem{
+			Subscription: NewSubscription(&item.Subscription),
+			MonthlyCost:  item.MonthlyCost,
+		}
+	}
+	return list
+}
+
+type CreateSubscriptionRequest struct {
+	ServiceName   string               `json:"service_name" validate:"required,servicename" example:"Yandex Plus"`
+	Category      *string              `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Price         int64                `json:"price" validate:"gte=0" example:"39900"`
+	Currency      models.Currency      `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	BillingPeriod models.BillingPeriod `json:"billing_period,omitempty" swaggertype:"string" example:"monthly"`
+	UserID        uuid.UUID            `json:"user_id" validate:"required" format:"uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     Month                `json:"start_date" validate:"required" swaggertype:"string" example:"2025-07"`
+	EndDate       *Month               `json:"end_date,omitempty" validate:"omitempty,monthgtefield=StartDate" swaggertype:"string" example:"2025-12"`
+	TrialEnd      *Month               `json:"trial_end,omitempty" validate:"omitempty,monthgtefield=StartDate,monthltefield=EndDate" swaggertype:"string" example:"2025-08"`
+}
+
+func (r CreateSubscriptionRequest) V1() dtos.CreateSubscriptionRequest {
+	return dtos.CreateSubscriptionRequest{
+		ServiceName:   r.ServiceName,
+		Category:      r.Category,
+		Price:         r.Price,
+		Currency:      r.Currency,
+		BillingPeriod: r.BillingPeriod,
+		UserID:        r.UserID,
+		StartDate:     r.StartDate.YearMonth(),
+		EndDate:       yearMonthPtr(r.EndDate),
+		TrialEnd:      yearMonthPtr(r.TrialEnd),
+	}
+}
+
+type UpdateSubscriptionRequest struct {
+	ServiceName        *string               `json:"service_name,omitempty" validate:"omitempty,servicename" example:"Yandex Plus"`
+	Category           *string               `json:"category,omitempty" validate:"omitempty,max=64" example:"streaming"`
+	Price              *int64                `json:"price,omitempty" validate:"omitempty,gte=0" example:"39900"`
+	PriceEffectiveFrom *Month                `json:"price_effective_from,omitempty" swaggertype:"string" example:"2026-01"`
+	Currency           *models.Currency      `json:"currency,omitempty" swaggertype:"string" example:"RUB"`
+	BillingPeriod      *models.BillingPeriod `json:"billing_period,omitempty" swaggertype:"string" example:"yearly"`
+	StartDate          *Month                `json:"start_date,omitempty" swaggertype:"string" example:"2025-07"`
+	EndDate            *Month                `json:"end_date,omitempty" validate:"omitempty,monthgtefield=StartDate" swaggertype:"string" example:"2025-12"`
+	TrialEnd           *Month                `json:"trial_end,omitempty" validate:"omitempty,monthgtefield=StartDate,monthltefield=EndDate" swaggertype:"string" example:"2025-08"`
+}
+
+func (r UpdateSubscriptionRequest) V1() dtos.UpdateSubscriptionRequest {
+	return dtos.UpdateSubscriptionRequest{
+		ServiceName:        r.ServiceName,
+		Category:           r.Category,
+		Price:              r.Price,
+		PriceEffectiveFrom: yearMonthPtr(r.PriceEffectiveFrom),
+		Currency:           r.Currency,
+		BillingPeriod:      r.BillingPeriod,
+		StartDate:          yearMonthPtr(r.StartDate),
+		EndDate:            yearMonthPtr(r.EndDate),
+		TrialEnd:           yearMonthPtr(r.TrialEnd),
+	}
+}
+
+// StatusChangeRequest is the optional body of the lifecycle actions. The
+// change takes effect from the current month unless Effective is given.
+type StatusChangeRequest struct {
+	Effective *Month `json:"effective,omitempty" swaggertype:"string" example:"2025-08"`
+	Reason    string `json:"reason,omitempty" validate:"max=500" example:"Travelling for two months"`
+}
+
+func (r StatusChangeRequest) V1() dtos.StatusChangeRequest {
+	return dtos.StatusChangeRequest{Effective: yearMonthPtr(r.Effective), Reason: r.Reason}
+}
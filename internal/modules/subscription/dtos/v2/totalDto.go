@@ -0,0 +1,102 @@
+package v2
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// TotalCostPeriod is the period of a total, both months inclusive.
+type TotalCostPeriod struct {
+	StartDate Month `query:"start_date" validate:"required"`
+	EndDate   Month `query:"end_date" validate:"required,monthgtefield=StartDate"`
+}
+
+// TotalCost amounts are in minor units of Currency. ByCurrency holds the
+// same charges summed in their original currencies.
+type TotalCost struct {
+	Total      int64               `json:"total" example:"239400"`
+	Count      int64               `json:"count" example:"2"`
+	Currency   string              `json:"currency" example:"RUB"`
+	ByCurrency map[string]int64    `json:"by_currency,omitempty"`
+	RatesUsed  []RateUsed          `json:"rates_used,omitempty"`
+	Breakdown  *TotalCostBreakdown `json:"breakdown,omitempty"`
+}
+
+// RateUsed records the exchange rate applied to charges of one month.
+type RateUsed struct {
+	Month    string  `json:"month" example:"2025-07"`
+	From     string  `json:"from" example:"USD"`
+	To       string  `json:"to" example:"RUB"`
+	Rate     float64 `json:"rate" example:"92.5"`
+	RateDate string  `json:"rate_date" example:"2025-07-01"`
+}
+
+type TotalCostBreakdown struct {
+	ByMonth        []MonthCost        `json:"by_month"`
+	BySubscription []SubscriptionCost `json:"by_subscription"`
+}
+
+type MonthCost struct {
+	Month  string `json:"month" example:"2025-07"`
+	Amount int64  `json:"amount" example:"39900"`
+}
+
+type SubscriptionCost struct {
+	SubscriptionID uuid.UUID   `json:"subscription_id" format:"uuid" example:"3f0c1a52-8a36-4f55-9a55-2c3c1d6f8e21"`
+	ServiceName    string      `json:"service_name" example:"Yandex Plus"`
+	Price          int64       `json:"price" example:"39900"`
+	Currency       string      `json:"currency" example:"RUB"`
+	BillingPeriod  string      `json:"billing_period" example:"monthly"`
+	Charges        int         `json:"charges" example:"6"`
+	Months         int         `json:"months" example:"6"`
+	Total          int64       `json:"total" example:"239400"`
+	ByMonth        []MonthCost `json:"by_month"`
+}
+
+func NewTotalCost(resp *dtos.TotalCostResponse) TotalCost {
+	total := TotalCost{
+		Total:      resp.Total,
+		Count:      resp.Count,
+		Currency:   resp.Currency,
+		ByCurrency: resp.ByCurrency,
+	}
+	for _, rate := range resp.RatesUsed {
+		total.RatesUsed = append(total.RatesUsed, RateUsed{
+			Month:    isoMonth(rate.Month),
+			From:     rate.From,
+			To:       rate.To,
+			Rate:     rate.Rate,
+			RateDate: rate.RateDate,
+		})
+	}
+	if resp.Breakdown != nil {
+		breakdown := &TotalCostBreakdown{
+			ByMonth:        newMonthCosts(resp.Breakdown.ByMonth),
+			BySubscription: make([]SubscriptionCost, len(resp.Breakdown.BySubscription)),
+		}
+		for i, cost := range resp.Breakdown.BySubscription {
+			breakdown.BySubscription[i] = SubscriptionCost{
+				SubscriptionID: cost.SubscriptionID,
+				ServiceName:    cost.ServiceName,
+				Price:          cost.Price,
+				Currency:       cost.Currency,
+				BillingPeriod:  cost.BillingPeriod,
+				Charges:        cost.Charges,
+				Months:         cost.Months,
+				Total:          cost.Total,
+				ByMonth:        newMonthCosts(cost.ByMonth),
+			}
+		}
+		total.Breakdown = breakdown
+	}
+	return total
+}
+
+func newMonthCosts(costs []dtos.MonthCost) []MonthCost {
+	months := make([]MonthCost, len(costs))
+	for i, cost := range costs {
+		months[i] = MonthCost{Month: isoMonth(cost.Month), Amount: cost.Amount}
+	}
+	return months
+}
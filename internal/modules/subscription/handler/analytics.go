@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// MonthlySpending godoc
+// @Summary Monthly spending
+// @Description Spending time series with one entry per calendar month of the period: the charges due in the month, converted at the exchange rate for the month, and how many subscriptions were active, started and ended in it. Computed in the database, so it suits charts over long periods. Amounts are in minor units.
+// @Tags subscriptions
+// @Produce json
+// @Param from query string true "First month (MM-YYYY)"
+// @Param to query string true "Last month (MM-YYYY), at most 120 months after from"
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param currency query string false "Currency to report in (ISO 4217, defaults to DEFAULT_CURRENCY)"
+// @Success 200 {object} dtos.MonthlySpendingResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/analytics/monthly [get]
+func (h *SubscriptionHandler) MonthlySpending(c echo.Context) error {
+	var period dtos.AnalyticsPeriod
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &period); err != nil {
+		return err
+	}
+	if err := c.Validate(period); err != nil {
+		return err
+	}
+	// Both months were checked by the validator.
+	from, _ := models.ParseYearMonth(period.From)
+	to, _ := models.ParseYearMonth(period.To)
+
+	filter, err := parseFilter(c, models.ParseYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	var currency models.Currency
+	if c.QueryParam("currency") != "" {
+		if currency, err = models.ParseCurrency(c.QueryParam("currency")); err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+	}
+
+	resp, err := h.service.MonthlySpending(from, to, filter, currency)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// SpendingBreakdown godoc
+// @Summary Spending breakdown
+// @Description Split the spending of a period by service name, category, user or month. Every group has its cost, its share of the total in percent, the shares adding up to 100, and the number of subscriptions charged in it. Charges are counted and converted as for the total cost. Categories are grouped ignoring case, and subscriptions without one under uncategorized. Services, categories and users are listed most expensive first; with top, the rest are summed into one group marked other. Amounts are in minor units.
+// @Tags subscriptions
+// @Produce json
+// @Param group_by query string true "Grouping: service_name, category, user_id or month"
+// @Param from query string true "First month (MM-YYYY)"
+// @Param to query string true "Last month (MM-YYYY), at most 120 months after from"
+// @Param top query int false "Only list the most expensive groups, up to 100, and sum the rest into other; not with group_by=month"
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param price_min query int false "Minimum price in minor units"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param active_at query string false "Only subscriptions running in this month (MM-YYYY)"
+// @Param start_from query string false "Earliest start month (MM-YYYY)"
+// @Param start_to query string false "Latest start month (MM-YYYY)"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param currency query string false "Currency to report in (ISO 4217, defaults to DEFAULT_CURRENCY)"
+// @Success 200 {object} dtos.SpendingBreakdownResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/analytics/breakdown [get]
+func (h *SubscriptionHandler) SpendingBreakdown(c echo.Context) error {
+	var query dtos.BreakdownQuery
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &query); err != nil {
+		return err
+	}
+	if err := c.Validate(query); err != nil {
+		return err
+	}
+	if query.GroupBy == dtos.GroupByMonth && query.Top > 0 {
+		return apperr.BadRequest("top cannot be combined with group_by=month")
+	}
+	// Both months were checked by the validator.
+	from, _ := models.ParseYearMonth(query.From)
+	to, _ := models.ParseYearMonth(query.To)
+
+	filter, err := parseFilter(c, models.ParseYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	var currency models.Currency
+	if c.QueryParam("currency") != "" {
+		if currency, err = models.ParseCurrency(c.QueryParam("currency")); err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+	}
+
+	resp, err := h.service.SpendingBreakdown(from, to, filter, currency, query.GroupBy, query.Top)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// SpendingForecast godoc
+// @Summary Spending forecast
+// @Description Forecast the monthly cost of the existing subscriptions over the coming months, starting with the current one. Subscriptions drop out after their end date and scheduled price changes apply from their month. With churn assumptions the expected cost is discounted by the chance that subscriptions are cancelled early, and every month gets a 90% confidence band. Amounts are in minor units.
+// @Tags subscriptions
+// @Produce json
+// @Param months query int false "Number of months, 1 to 60 (default 12)"
+// @Param churn query []string false "Monthly cancellation probability from 0 to 1, for every service (0.02) or for one (Netflix:0.1); repeat for several services" collectionFormat(multi)
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param currency query string false "Currency to report in (ISO 4217, defaults to DEFAULT_CURRENCY)"
+// @Success 200 {object} dtos.ForecastResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/analytics/forecast [get]
+func (h *SubscriptionHandler) SpendingForecast(c echo.Context) error {
+	var query dtos.ForecastQuery
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &query); err != nil {
+		return err
+	}
+	if err := c.Validate(query); err != nil {
+		return err
+	}
+
+	churn, err := parseChurn(c.QueryParams()["churn"])
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	filter, err := parseFilter(c, models.ParseYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	var currency models.Currency
+	if c.QueryParam("currency") != "" {
+		if currency, err = models.ParseCurrency(c.QueryParam("currency")); err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+	}
+
+	resp, err := h.service.Forecast(query.Months, filter, currency, churn)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// parseChurn reads churn assumptions such as "0.02" for every service and
+// "Netflix:0.1" for one. Service names may contain colons, so the
+// probability follows the last one. It returns nil without assumptions.
+func parseChurn(values []string) (*dtos.ChurnAssumptions, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	churn := &dtos.ChurnAssumptions{ByService: make(map[string]float64)}
+	for _, value := range values {
+		service, probability := "", value
+		if i := strings.LastIndexByte(value, ':'); i >= 0 {
+			service, probability = strings.TrimSpace(value[:i]), value[i+1:]
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(probability), 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("invalid churn %q, expected a probability from 0 to 1, optionally after a service name and a colon", value)
+		}
+		if service == "" {
+			churn.Default = p
+		} else {
+			churn.ByService[strings.ToLower(service)] = p
+		}
+	}
+	return churn, nil
+}
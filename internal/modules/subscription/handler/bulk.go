@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+)
+
+// BulkSubscriptions godoc
+// @Summary Bulk create, update and delete
+// @Description Apply a list of create, update and delete operations in the order given. In atomic mode (default) all of them are applied in one transaction or none is; in best_effort mode each one is applied on its own. Every item gets the status the single-item endpoint would have returned; operations rolled back in atomic mode get 424. Creates are subject to the duplicate guard like single creates, also against earlier creates of the same request: they get 409 in reject mode and warnings in warn mode.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param request body dtos.BulkRequest true "Operations"
+// @Success 200 {object} dtos.BulkResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 413 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/bulk [post]
+func (h *SubscriptionHandler) Bulk(c echo.Context) error {
+	var req dtos.BulkRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if len(req.Operations) == 0 {
+		return apperr.BadRequest("operations must not be empty")
+	}
+	if len(req.Operations) > h.bulkMaxOperations {
+		return apperr.New(
+			apperr.TooLarge,
+			"too-many-operations",
+			fmt.Sprintf("at most %d operations are allowed per request", h.bulkMaxOperations),
+		)
+	}
+
+	outcomes, committed, err := h.service.Bulk(req, c.Validate)
+	if err != nil {
+		return err
+	}
+
+	resp := dtos.BulkResponse{
+		Mode:      req.Mode,
+		Committed: committed,
+		Results:   make([]dtos.BulkItemResult, len(outcomes)),
+	}
+	if resp.Mode == "" {
+		resp.Mode = dtos.BulkAtomic
+	}
+	languages := apperr.AcceptedLanguages(c.Request().Header.Get("Accept-Language"))
+	for i, outcome := range outcomes {
+		result := dtos.BulkItemResult{
+			Index:        i,
+			Op:           outcome.Op,
+			ID:           outcome.ID,
+			Status:       bulkStatus(outcome),
+			Warnings:     outcome.Warnings,
+			Subscription: outcome.Subscription,
+		}
+		if outcome.Err != nil {
+			result.Error = outcome.Err.Error()
+			if result.Status >= http.StatusInternalServerError {
+				// Like the single-item endpoints, do not show unexpected errors.
+				log.Printf("Bulk %s operation %d failed: %v", outcome.Op, i, outcome.Err)
+				result.Error = http.StatusText(result.Status)
+			} else {
+				result.Errors = apperr.FieldsOf(outcome.Err, languages)
+			}
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results[i] = result
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// bulkStatus maps the outcome of an operation to the status code of the
+// matching single-item endpoint.
+func bulkStatus(outcome service.BulkOutcome) int {
+	switch err := outcome.Err; {
+	case err == nil && outcome.Op == dtos.BulkCreate:
+		return http.StatusCreated
+	case err == nil && outcome.Op == dtos.BulkDelete:
+		return http.StatusNoContent
+	case err == nil:
+		return http.StatusOK
+	default:
+		return apperr.Status(err)
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+)
+
+// bulkService fails every operation with err and counts the requests it
+// gets.
+type bulkService struct {
+	service.SubscriptionService
+	err   error
+	calls int
+}
+
+func (s *bulkService) Bulk(req dtos.BulkRequest, validate func(any) error) ([]service.BulkOutcome, bool, error) {
+	s.calls++
+	outcomes := make([]service.BulkOutcome, len(req.Operations))
+	for i, op := range req.Operations {
+		outcomes[i] = service.BulkOutcome{Op: op.Op, Err: s.err}
+	}
+	return outcomes, false, nil
+}
+
+func bulkRequest(operations int) string {
+	ops := strings.TrimSuffix(strings.Repeat(`{"op": "delete"},`, operations), ",")
+	return fmt.Sprintf(`{"mode": "best_effort", "operations": [%s]}`, ops)
+}
+
+func serveBulk(h *SubscriptionHandler, body string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subs/bulk", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return rec, h.Bulk(echo.New().NewContext(req, rec))
+}
+
+func TestBulkOperationLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations int
+		wantStatus int
+	}{
+		{name: "empty", operations: 0, wantStatus: http.StatusBadRequest},
+		{name: "at the limit", operations: 3, wantStatus: http.StatusOK},
+		{name: "over the limit", operations: 4, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &bulkService{err: service.ErrSubscriptionNotFound}
+			rec, err := serveBulk(NewSubscriptionHandler(svc, false, 3), bulkRequest(tt.operations))
+
+			status := rec.Code
+			if err != nil {
+				status = apperr.Status(err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%v)", status, tt.wantStatus, err)
+			}
+			wantCalls := 0
+			if tt.wantStatus == http.StatusOK {
+				wantCalls = 1
+			}
+			if svc.calls != wantCalls {
+				t.Errorf("service called %d times, want %d", svc.calls, wantCalls)
+			}
+		})
+	}
+}
+
+func TestBulkItemErrors(t *testing.T) {
+	field := apperr.FieldError{Field: "price", Message: "price must be 0 or greater"}
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantError  string
+		wantFields []apperr.FieldError
+	}{
+		{
+			name:       "field errors",
+			err:        fmt.Errorf("%w: %w", service.ErrInvalidBulkOperation, apperr.Validation("validation failed", field)),
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid bulk operation: validation failed: price must be 0 or greater",
+			wantFields: []apperr.FieldError{field},
+		},
+		{
+			name:       "not found",
+			err:        service.ErrSubscriptionNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "subscription not found",
+		},
+		{
+			name:       "unexpected error",
+			err:        fmt.Errorf("connection reset"),
+			wantStatus: http.StatusInternalServerError,
+			wantError:  http.StatusText(http.StatusInternalServerError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := serveBulk(NewSubscriptionHandler(&bulkService{err: tt.err}, false, 3), bulkRequest(1))
+			if err != nil {
+				t.Fatalf("Bulk() error = %v", err)
+			}
+			var resp dtos.BulkResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			result := resp.Results[0]
+			if result.Status != tt.wantStatus || result.Error != tt.wantError || !reflect.DeepEqual(result.Errors, tt.wantFields) {
+				t.Errorf("result = %d %q %+v, want %d %q %+v", result.Status, result.Error, result.Errors, tt.wantStatus, tt.wantError, tt.wantFields)
+			}
+			if resp.Failed != 1 || resp.Succeeded != 0 {
+				t.Errorf("failed %d, succeeded %d, want 1 and 0", resp.Failed, resp.Succeeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+)
+
+// Duplicates godoc
+// @Summary Report duplicate subscriptions
+// @Description Find subscriptions of the same user whose service names are equal once case, spaces and punctuation are ignored, such as "Yandex Plus" and "yandex-plus", and whose periods overlap, so they are counted twice in the total
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Success 200 {object} dtos.DuplicatesResponse
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/duplicates [get]
+func (h *SubscriptionHandler) Duplicates(c echo.Context) error {
+	userID := c.QueryParam("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return apperr.BadRequest("invalid user_id")
+		}
+	}
+
+	resp, err := h.service.Duplicates(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// warnDuplicates adds a Warning header and a Link to each likely duplicate,
+// which lives under basePath.
+func warnDuplicates(c echo.Context, duplicates []models.Subscription, basePath string) {
+	header := c.Response().Header()
+	for _, sub := range duplicates {
+		header.Add("Warning", fmt.Sprintf("299 - %q", service.DescribeDuplicate(sub)))
+		header.Add("Link", fmt.Sprintf(`<%s/%s>; rel="duplicate"`, basePath, sub.ID))
+	}
+}
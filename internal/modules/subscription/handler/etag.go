@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+var errIfMatchRequired = apperr.New(apperr.PreconditionRequired, "if-match-required", "If-Match header is required")
+
+// etag is the entity tag of a subscription, derived from its version.
+func etag(sub *models.Subscription) string {
+	return `"` + strconv.FormatInt(sub.Version, 10) + `"`
+}
+
+// jsonWithETag writes sub along with its ETag header.
+func jsonWithETag(c echo.Context, status int, sub *models.Subscription) error {
+	c.Response().Header().Set("ETag", etag(sub))
+	return c.JSON(status, sub)
+}
+
+// ifMatchVersions returns the versions listed in the If-Match header and
+// whether the header was sent. "*" matches any version and yields nil; tags
+// that are weak or not ours never match, so they yield an empty list.
+func ifMatchVersions(c echo.Context) ([]int64, bool) {
+	header := c.Request().Header.Get("If-Match")
+	if header == "" {
+		return nil, false
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if version, ok := parseETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions, true
+}
+
+// notModified reports whether the If-None-Match header matches sub. Weak
+// tags are compared by their value, as RFC 9110 prescribes for GET.
+func notModified(c echo.Context, sub *models.Subscription) bool {
+	header := c.Request().Header.Get("If-None-Match")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" {
+			return true
+		}
+		if version, ok := parseETag(tag); ok && version == sub.Version {
+			return true
+		}
+	}
+	return false
+}
+
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil
+}
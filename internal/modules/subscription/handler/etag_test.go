@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+func contextWith(header, value string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestETag(t *testing.T) {
+	for version, want := range map[int64]string{1: `"1"`, 42: `"42"`} {
+		if got := etag(&models.Subscription{Version: version}); got != want {
+			t.Errorf("etag(version %d) = %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		header   string
+		want     []int64
+		wantSent bool
+	}{
+		{header: "", want: nil, wantSent: false},
+		{header: `"3"`, want: []int64{3}, wantSent: true},
+		{header: `"3", "4"`, want: []int64{3, 4}, wantSent: true},
+		{header: `*`, want: nil, wantSent: true},
+		{header: `"3", *`, want: nil, wantSent: true},
+		{header: `W/"3"`, want: []int64{}, wantSent: true},
+		{header: `"abc", 3`, want: []int64{}, wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, sent := ifMatchVersions(contextWith("If-Match", tt.header))
+			if sent != tt.wantSent || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ifMatchVersions(%q) = %#v, %v, want %#v, %v", tt.header, got, sent, tt.want, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	sub := &models.Subscription{Version: 3}
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"2", "3"`, want: true},
+		{header: `"2"`, want: false},
+		{header: `*`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := notModified(contextWith("If-None-Match", tt.header), sub); got != tt.want {
+				t.Errorf("notModified(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
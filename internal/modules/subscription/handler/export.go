@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/utils/export"
+)
+
+// listColumns are the columns a subscription list can be exported with.
+var listColumns = map[string]func(item dtos.SubscriptionListItem) any{
+	"id":             func(item dtos.SubscriptionListItem) any { return item.ID.String() },
+	"service_name":   func(item dtos.SubscriptionListItem) any { return item.ServiceName },
+	"category":       func(item dtos.SubscriptionListItem) any { return optionalString(item.Category) },
+	"price":          func(item dtos.SubscriptionListItem) any { return item.Price },
+	"currency":       func(item dtos.SubscriptionListItem) any { return string(item.Currency) },
+	"billing_period": func(item dtos.SubscriptionListItem) any { return string(item.BillingPeriod) },
+	"monthly_cost":   func(item dtos.SubscriptionListItem) any { return item.MonthlyCost },
+	"user_id":        func(item dtos.SubscriptionListItem) any { return item.UserID.String() },
+	"start_date":     func(item dtos.SubscriptionListItem) any { return item.StartDate.String() },
+	"end_date":       func(item dtos.SubscriptionListItem) any { return optionalMonth(item.EndDate) },
+	"trial_end":      func(item dtos.SubscriptionListItem) any { return optionalMonth(item.TrialEnd) },
+	"status":         func(item dtos.SubscriptionListItem) any { return string(item.Status) },
+	"version":        func(item dtos.SubscriptionListItem) any { return item.Version },
+	"created_at":     func(item dtos.SubscriptionListItem) any { return item.CreatedAt.UTC().Format(time.RFC3339) },
+	"updated_at":     func(item dtos.SubscriptionListItem) any { return item.UpdatedAt.UTC().Format(time.RFC3339) },
+}
+
+var defaultListColumns = []string{
+	"id", "service_name", "price", "currency", "billing_period", "monthly_cost",
+	"user_id", "start_date", "end_date", "status",
+}
+
+// costColumns are the columns of a total cost export by subscription.
+var costColumns = map[string]func(cost dtos.SubscriptionCost) any{
+	"subscription_id": func(cost dtos.SubscriptionCost) any { return cost.SubscriptionID.String() },
+	"service_name":    func(cost dtos.SubscriptionCost) any { return cost.ServiceName },
+	"price":           func(cost dtos.SubscriptionCost) any { return cost.Price },
+	"currency":        func(cost dtos.SubscriptionCost) any { return cost.Currency },
+	"billing_period":  func(cost dtos.SubscriptionCost) any { return cost.BillingPeriod },
+	"charges":         func(cost dtos.SubscriptionCost) any { return cost.Charges },
+	"months":          func(cost dtos.SubscriptionCost) any { return cost.Months },
+	"total":           func(cost dtos.SubscriptionCost) any { return cost.Total },
+}
+
+var defaultCostColumns = []string{
+	"subscription_id", "service_name", "price", "currency", "billing_period", "charges", "months", "total",
+}
+
+// monthColumns are the columns of a total cost export by month.
+var monthColumns = map[string]func(cost dtos.MonthCost) any{
+	"month":  func(cost dtos.MonthCost) any { return cost.Month },
+	"amount": func(cost dtos.MonthCost) any { return cost.Amount },
+}
+
+var defaultMonthColumns = []string{"month", "amount"}
+
+// negotiateFormat picks the output format from the format parameter or the
+// Accept header.
+func negotiateFormat(c echo.Context) (export.Format, error) {
+	return export.Negotiate(c.QueryParam("format"), c.Request().Header.Get(echo.HeaderAccept))
+}
+
+// exportList streams every subscription matching filter as a table,
+// ignoring pagination.
+func (h *SubscriptionHandler) exportList(c echo.Context, format export.Format, filter dtos.SubscriptionFilter) error {
+	columns, err := export.ParseColumns(c.QueryParam("columns"), listColumns, defaultListColumns)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	return writeTable(c, format, "subscriptions", columns, func(write func(values []any) error) error {
+		return h.service.Export(filter, func(item dtos.SubscriptionListItem) error {
+			return write(rowOf(item, columns, listColumns))
+		})
+	})
+}
+
+// exportTotalCost writes the breakdown of resp as a table, one row per
+// subscription or, with rows=months, per month.
+func exportTotalCost(c echo.Context, format export.Format, resp *dtos.TotalCostResponse) error {
+	var breakdown dtos.TotalCostBreakdown
+	if resp.Breakdown != nil {
+		breakdown = *resp.Breakdown
+	}
+
+	switch c.QueryParam("rows") {
+	case "", "subscriptions":
+		columns, err := export.ParseColumns(c.QueryParam("columns"), costColumns, defaultCostColumns)
+		if err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+		return writeTable(c, format, "total-by-subscription", columns, func(write func(values []any) error) error {
+			for _, cost := range breakdown.BySubscription {
+				if err := write(rowOf(cost, columns, costColumns)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case "months":
+		columns, err := export.ParseColumns(c.QueryParam("columns"), monthColumns, defaultMonthColumns)
+		if err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+		return writeTable(c, format, "total-by-month", columns, func(write func(values []any) error) error {
+			for _, cost := range breakdown.ByMonth {
+				if err := write(rowOf(cost, columns, monthColumns)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return apperr.BadRequest("invalid rows, expected subscriptions or months")
+}
+
+// writeTable writes a header of columns followed by the rows produced by
+// rows. Writers buffer their first bytes, so an error before the first rows
+// still reaches the client as a problem; later errors cut the download short.
+func writeTable(c echo.Context, format export.Format, name string, columns []string, rows func(write func(values []any) error) error) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	err := func() error {
+		w, err := export.NewWriter(format, res)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteHeader(columns); err != nil {
+			return err
+		}
+		if err := rows(w.WriteRow); err != nil {
+			return err
+		}
+		return w.Close()
+	}()
+	if err != nil {
+		if !res.Committed {
+			res.Header().Del(echo.HeaderContentDisposition)
+		}
+		return err
+	}
+	if !res.Committed {
+		res.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
+
+func rowOf[T any](value T, columns []string, extract map[string]func(T) any) []any {
+	row := make([]any, len(columns))
+	for i, column := range columns {
+		row[i] = extract[column](value)
+	}
+	return row
+}
+
+func optionalString(value *string) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func optionalMonth(month *models.YearMonth) any {
+	if month == nil {
+		return nil
+	}
+	return month.String()
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// parseFilter reads the subscription filter shared by the list and total
+// endpoints from the query string. parseMonth reads the month parameters in
+// the format of the API version.
+func parseFilter(c echo.Context, parseMonth func(string) (models.YearMonth, error)) (dtos.SubscriptionFilter, error) {
+	filter := dtos.SubscriptionFilter{
+		UserID:      c.QueryParam("user_id"),
+		ServiceName: c.QueryParam("service_name"),
+		NameMatch:   c.QueryParam("name_match"),
+		Category:    c.QueryParam("category"),
+		Status:      c.QueryParam("status"),
+	}
+
+	if filter.UserID != "" {
+		if _, err := uuid.Parse(filter.UserID); err != nil {
+			return filter, fmt.Errorf("invalid user_id")
+		}
+	}
+
+	switch filter.NameMatch {
+	case "", dtos.NameMatchContains, dtos.NameMatchExact:
+	default:
+		return filter, fmt.Errorf("invalid name_match, expected contains or exact")
+	}
+
+	switch models.SubscriptionStatus(filter.Status) {
+	case "", models.StatusTrial, models.StatusActive, models.StatusPaused, models.StatusCancelled:
+	default:
+		return filter, fmt.Errorf("invalid status, expected trial, active, paused or cancelled")
+	}
+
+	var err error
+	if filter.PriceMin, err = queryInt64(c, "price_min"); err != nil {
+		return filter, err
+	}
+	if filter.PriceMax, err = queryInt64(c, "price_max"); err != nil {
+		return filter, err
+	}
+	if filter.PriceMin != nil && filter.PriceMax != nil && *filter.PriceMin > *filter.PriceMax {
+		return filter, fmt.Errorf("price_min must not be greater than price_max")
+	}
+
+	if filter.ActiveAt, err = queryYearMonth(c, "active_at", parseMonth); err != nil {
+		return filter, err
+	}
+	if filter.StartFrom, err = queryYearMonth(c, "start_from", parseMonth); err != nil {
+		return filter, err
+	}
+	if filter.StartTo, err = queryYearMonth(c, "start_to", parseMonth); err != nil {
+		return filter, err
+	}
+
+	if value := c.QueryParam("has_end_date"); value != "" {
+		hasEndDate, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid has_end_date, expected true or false")
+		}
+		filter.HasEndDate = &hasEndDate
+	}
+
+	if filter.Sort, err = dtos.ParseSort(c.QueryParam("sort")); err != nil {
+		return filter, fmt.Errorf("invalid sort: %w", err)
+	}
+
+	return filter, nil
+}
+
+func queryInt64(c echo.Context, name string) (*int64, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected an integer amount in minor units", name)
+	}
+	return &n, nil
+}
+
+func queryYearMonth(c echo.Context, name string, parseMonth func(string) (models.YearMonth, error)) (*models.YearMonth, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	month, err := parseMonth(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &month, nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
+)
+
+// listService records the filter of the last list request.
+type listService struct {
+	service.SubscriptionService
+	filter *dtos.SubscriptionFilter
+}
+
+func (s *listService) List(filter dtos.SubscriptionFilter, page dtos.PageRequest) ([]dtos.SubscriptionListItem, *pagination.Meta, error) {
+	s.filter = &filter
+	return nil, &pagination.Meta{}, nil
+}
+
+func TestListFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+		check   func(dtos.SubscriptionFilter) bool
+	}{
+		{
+			name:  "no filter",
+			query: "",
+			check: func(f dtos.SubscriptionFilter) bool {
+				return f.ServiceName == "" && f.NameMatch == "" && f.Status == "" && f.PriceMin == nil && f.PriceMax == nil
+			},
+		},
+		{
+			name:  "exact name",
+			query: "service_name=Netflix&name_match=exact",
+			check: func(f dtos.SubscriptionFilter) bool {
+				return f.ServiceName == "Netflix" && f.NameMatch == dtos.NameMatchExact
+			},
+		},
+		{name: "contains name", query: "name_match=contains", check: func(f dtos.SubscriptionFilter) bool { return f.NameMatch == dtos.NameMatchContains }},
+		{name: "unknown name match", query: "service_name=Netflix&name_match=prefix", wantErr: "invalid name_match"},
+		{name: "name match is case sensitive", query: "name_match=EXACT", wantErr: "invalid name_match"},
+		{
+			name:  "price range",
+			query: "price_min=100&price_max=100",
+			check: func(f dtos.SubscriptionFilter) bool {
+				return f.PriceMin != nil && *f.PriceMin == 100 && f.PriceMax != nil && *f.PriceMax == 100
+			},
+		},
+		{name: "only price max", query: "price_max=0", check: func(f dtos.SubscriptionFilter) bool { return f.PriceMin == nil && *f.PriceMax == 0 }},
+		{name: "price min over price max", query: "price_min=500&price_max=499", wantErr: "price_min must not be greater than price_max"},
+		{name: "price not in minor units", query: "price_min=4.99", wantErr: "invalid price_min"},
+		{name: "trial", query: "status=trial", check: func(f dtos.SubscriptionFilter) bool { return f.Status == "trial" }},
+		{name: "active", query: "status=active", check: func(f dtos.SubscriptionFilter) bool { return f.Status == "active" }},
+		{name: "paused", query: "status=paused", check: func(f dtos.SubscriptionFilter) bool { return f.Status == "paused" }},
+		{name: "cancelled", query: "status=cancelled", check: func(f dtos.SubscriptionFilter) bool { return f.Status == "cancelled" }},
+		{name: "unknown status", query: "status=expired", wantErr: "invalid status"},
+		{name: "american spelling", query: "status=canceled", wantErr: "invalid status"},
+		{name: "invalid user", query: "user_id=42", wantErr: "invalid user_id"},
+		{name: "invalid has_end_date", query: "has_end_date=maybe", wantErr: "invalid has_end_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &listService{}
+			h := NewSubscriptionHandler(svc, false, 100)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/subs/list?"+tt.query, nil)
+			err := h.List(echo.New().NewContext(req, httptest.NewRecorder()))
+
+			if tt.wantErr != "" {
+				if status := apperr.Status(err); status != http.StatusBadRequest || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %d %v, want 400 %q", status, err, tt.wantErr)
+				}
+				if svc.filter != nil {
+					t.Errorf("service called with %+v after an invalid filter", *svc.filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if svc.filter == nil {
+				t.Fatal("service not called")
+			}
+			if !tt.check(*svc.filter) {
+				t.Errorf("filter = %+v", *svc.filter)
+			}
+		})
+	}
+}
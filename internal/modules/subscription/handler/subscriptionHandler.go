@@ -2,49 +2,78 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+	"github.com/Ilmyrat1822/subs/utils/export"
+	"github.com/Ilmyrat1822/subs/utils/jsonpatch"
+)
+
+var errUnsupportedPatch = apperr.New(
+	apperr.UnsupportedMediaType,
+	"unsupported-patch-format",
+	"Content-Type must be "+jsonpatch.MergePatchContentType+" or "+jsonpatch.JSONPatchContentType,
 )
 
 type SubscriptionHandler struct {
-	service service.SubscriptionService
+	service           service.SubscriptionService
+	requireIfMatch    bool
+	bulkMaxOperations int
 }
 
-func NewSubscriptionHandler(service service.SubscriptionService) *SubscriptionHandler {
-	return &SubscriptionHandler{service: service}
+// NewSubscriptionHandler creates the handler. With requireIfMatch set, PUT
+// and DELETE without an If-Match header are rejected with 428.
+// bulkMaxOperations limits the size of bulk requests.
+func NewSubscriptionHandler(service service.SubscriptionService, requireIfMatch bool, bulkMaxOperations int) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		service:           service,
+		requireIfMatch:    requireIfMatch,
+		bulkMaxOperations: bulkMaxOperations,
+	}
 }
 
 // CreateSubscription godoc
 // @Summary Create subscription
-// @Description Create a new subscription
+// @Description Create a new subscription. Depending on the duplicate guard, an overlapping subscription of the user to the same service is rejected with 409 or reported in Warning and Link headers.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body dtos.CreateSubscriptionRequest true "Subscription data"
+// @Param Idempotency-Key header string false "Unique key of this request; retries with the same key and body return the original response"
 // @Success 201 {object} models.Subscription
-// @Failure 400 {object} dtos.ErrorResponse
-// @Failure 500 {object} dtos.ErrorResponse
-// @Router /api/subs [post]
+// @Header 201 {string} Warning "Likely duplicate subscription, with the guard in warn mode"
+// @Failure 400 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs [post]
 func (h *SubscriptionHandler) Create(c echo.Context) error {
 	var req dtos.CreateSubscriptionRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return err
 	}
 	if err := c.Validate(req); err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return err
 	}
 
-	sub, err := h.service.Create(req)
+	sub, duplicates, err := h.service.Create(req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return err
 	}
 
-	return c.JSON(http.StatusCreated, sub)
+	warnDuplicates(c, duplicates, "/api/v1/subs")
+	return jsonWithETag(c, http.StatusCreated, sub)
 }
 
 // GetSubscription godoc
@@ -52,44 +81,92 @@ func (h *SubscriptionHandler) Create(c echo.Context) error {
 // @Description Get subscription by ID
 // @Tags subscriptions
 // @Produce json
-// @Param id path int true "Subscription ID"
+// @Param id path string true "Subscription ID (UUID)"
+// @Param If-None-Match header string false "ETag of a cached copy; 304 is returned while it is current"
 // @Success 200 {object} models.Subscription
-// @Failure 404 {object} dtos.ErrorResponse
-// @Failure 500 {object} dtos.ErrorResponse
-// @Router /api/subs/{id} [get]
+// @Success 304
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id} [get]
 func (h *SubscriptionHandler) Get(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "invalid id"})
+		return apperr.BadRequest("invalid id")
 	}
 
 	sub, err := h.service.Get(id)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "not found"})
+		return err
 	}
 
-	return c.JSON(http.StatusOK, sub)
+	if notModified(c, sub) {
+		c.Response().Header().Set("ETag", etag(sub))
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return jsonWithETag(c, http.StatusOK, sub)
 }
 
 // ListSubscriptions godoc
 // @Summary List subscriptions
+// @Description List subscriptions a page at a time as JSON, or export all matching subscriptions as CSV, NDJSON or XLSX, chosen by the format parameter or the Accept header. Exports ignore pagination and are streamed row by row.
 // @Tags subscriptions
 // @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param user_id query string false "User ID (UUID)"
 // @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param price_min query int false "Minimum price in minor units"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param active_at query string false "Only subscriptions running in this month (MM-YYYY)"
+// @Param start_from query string false "Earliest start month (MM-YYYY)"
+// @Param start_to query string false "Latest start month (MM-YYYY)"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param sort query string false "Comma-separated sort fields, - for descending: service_name, price, currency, status, start_date, end_date, created_at, updated_at (default -created_at)"
 // @Param limit query int false "Limit (default 20, max 100)"
-// @Param offset query int false "Offset"
+// @Param offset query int false "Offset, ignored when cursor is given"
+// @Param cursor query string false "Opaque cursor from meta.next or meta.prev of a previous page"
+// @Param include_total query bool false "Count matching rows (default true with offset, false with cursor)"
+// @Param format query string false "Output format: json, csv, ndjson or xlsx; overrides the Accept header"
+// @Param columns query string false "Comma-separated columns of csv, ndjson and xlsx output, in order: id, service_name, category, price, currency, billing_period, monthly_cost, user_id, start_date, end_date, trial_end, status, version, created_at, updated_at"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperr.Problem
+// @Failure 406 {object} apperr.Problem
+// @Router /api/v1/subs/list [get]
 func (h *SubscriptionHandler) List(c echo.Context) error {
-	userID := c.QueryParam("user_id")
-	serviceName := c.QueryParam("service_name")
+	filter, err := parseFilter(c, models.ParseYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
 
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	format, err := negotiateFormat(c)
+	if err != nil {
+		return err
+	}
+	if format != export.JSON {
+		return h.exportList(c, format, filter)
+	}
+
+	page := dtos.PageRequest{Cursor: c.QueryParam("cursor")}
+	page.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	page.Offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	if value := c.QueryParam("include_total"); value != "" {
+		includeTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return apperr.BadRequest("invalid include_total, expected true or false")
+		}
+		page.IncludeTotal = &includeTotal
+	}
 
-	subs, meta, err := h.service.List(userID, serviceName, limit, offset)
+	subs, meta, err := h.service.List(filter, page)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -100,98 +177,535 @@ func (h *SubscriptionHandler) List(c echo.Context) error {
 
 // UpdateSubscription godoc
 // @Summary Update subscription
-// @Description Update subscription by ID
+// @Description Update subscription by ID. A new price applies from price_effective_from, the current month by default; earlier months keep their price.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
-// @Param id path int true "Subscription ID"
+// @Param id path string true "Subscription ID (UUID)"
 // @Param subscription body dtos.UpdateSubscriptionRequest true "Updated subscription data"
+// @Param If-Match header string false "ETag the update is based on"
 // @Success 200 {object} models.Subscription
-// @Failure 400 {object} dtos.ErrorResponse
-// @Failure 404 {object} dtos.ErrorResponse
-// @Failure 500 {object} dtos.ErrorResponse
-// @Router /api/subs/{id} [put]
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 428 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id} [put]
 func (h *SubscriptionHandler) Update(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "invalid id"})
+		return apperr.BadRequest("invalid id")
+	}
+
+	ifMatch, sent := ifMatchVersions(c)
+	if !sent && h.requireIfMatch {
+		return errIfMatchRequired
 	}
 
 	var req dtos.UpdateSubscriptionRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
 	}
 
-	sub, err := h.service.Update(id, req)
+	sub, err := h.service.Update(id, req, ifMatch)
 	if err != nil {
-		if err.Error() == "subscription not found" {
-			return c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: err.Error()})
+		return err
+	}
+
+	return jsonWithETag(c, http.StatusOK, sub)
+}
+
+// PatchSubscription godoc
+// @Summary Patch subscription
+// @Description Change a subscription with a JSON Merge Patch (RFC 7396, application/merge-patch+json), where null clears a field such as end_date, or a JSON Patch (RFC 6902, application/json-patch+json), including test operations. The patch applies to the editable fields shown in dtos.SubscriptionDocument, and the result is validated as a whole.
+// @Tags subscriptions
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param patch body dtos.SubscriptionDocument true "Merge patch, or an array of JSON Patch operations"
+// @Param If-Match header string false "ETag the patch is based on"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 415 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 428 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id} [patch]
+func (h *SubscriptionHandler) Patch(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch mediaType {
+	case jsonpatch.MergePatchContentType:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.JSONPatchContentType:
+		apply = jsonpatch.Apply
+	default:
+		return errUnsupportedPatch
+	}
+
+	ifMatch, sent := ifMatchVersions(c)
+	if !sent && h.requireIfMatch {
+		return errIfMatchRequired
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	sub, err := h.service.Patch(id, func(doc []byte) ([]byte, error) {
+		patched, err := apply(doc, patch)
+		if errors.Is(err, jsonpatch.ErrMalformedPatch) {
+			return nil, apperr.BadRequest(err.Error())
 		}
-		return c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return patched, err
+	}, ifMatch, c.Validate)
+	if err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, sub)
+	return jsonWithETag(c, http.StatusOK, sub)
 }
 
 // DeleteSubscription godoc
 // @Summary Delete subscription
-// @Description Delete subscription by ID
+// @Description Move subscription to the trash. It can be restored until it is purged after the retention period.
 // @Tags subscriptions
-// @Param id path int true "Subscription ID"
+// @Param id path string true "Subscription ID (UUID)"
+// @Param If-Match header string false "ETag the deletion is based on"
 // @Success 204
-// @Failure 404 {object} dtos.ErrorResponse
-// @Failure 500 {object} dtos.ErrorResponse
-// @Router /api/subs/{id} [delete]
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 428 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id} [delete]
 func (h *SubscriptionHandler) Delete(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error: "invalid id",
-		})
+		return apperr.BadRequest("invalid id")
 	}
 
-	err = h.service.Delete(id)
+	ifMatch, sent := ifMatchVersions(c)
+	if !sent && h.requireIfMatch {
+		return errIfMatchRequired
+	}
+
+	err = h.service.Delete(id, ifMatch)
 	if err != nil {
-		if errors.Is(err, service.ErrSubscriptionNotFound) {
-			return c.JSON(http.StatusNotFound, dtos.ErrorResponse{
-				Error: err.Error(),
-			})
-		}
-		return c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
-			Error: err.Error(),
-		})
+		return err
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// ListTrash godoc
+// @Summary List deleted subscriptions
+// @Description List subscriptions in the trash, most recently deleted first, with the time each will be purged
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/trash [get]
+func (h *SubscriptionHandler) Trash(c echo.Context) error {
+	userID := c.QueryParam("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return apperr.BadRequest("invalid user_id")
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+
+	items, meta, err := h.service.Trash(userID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": items,
+		"meta": meta,
+	})
+}
+
+// RestoreSubscription godoc
+// @Summary Restore subscription
+// @Description Move a deleted subscription out of the trash. Like a new subscription, it goes through the duplicate guard: an overlapping subscription of the user to the same service is rejected with 409 or reported in Warning and Link headers.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param If-Match header string false "ETag the restore is based on"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "Version of the subscription"
+// @Header 200 {string} Warning "Likely duplicate subscription, with the guard in warn mode"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/restore [post]
+func (h *SubscriptionHandler) Restore(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	ifMatch, _ := ifMatchVersions(c)
+	sub, duplicates, err := h.service.Restore(id, ifMatch)
+	if err != nil {
+		return err
+	}
+
+	warnDuplicates(c, duplicates, "/api/v1/subs")
+	return jsonWithETag(c, http.StatusOK, sub)
+}
+
+// PurgeTrash godoc
+// @Summary Purge trash
+// @Description Permanently remove the subscriptions that have been in the trash for longer than the retention period. The same purge also runs in the background.
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {object} dtos.PurgeResponse
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/trash/purge [post]
+func (h *SubscriptionHandler) PurgeTrash(c echo.Context) error {
+	purged, err := h.service.PurgeTrash()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dtos.PurgeResponse{Purged: purged})
+}
+
+// PauseSubscription godoc
+// @Summary Pause subscription
+// @Description Pause an active subscription. Charges due while paused are not counted.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body dtos.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/pause [post]
+func (h *SubscriptionHandler) Pause(c echo.Context) error {
+	return h.changeStatus(c, h.service.Pause)
+}
+
+// ResumeSubscription godoc
+// @Summary Resume subscription
+// @Description Resume a paused subscription
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body dtos.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/resume [post]
+func (h *SubscriptionHandler) Resume(c echo.Context) error {
+	return h.changeStatus(c, h.service.Resume)
+}
+
+// CancelSubscription godoc
+// @Summary Cancel subscription
+// @Description Cancel a subscription. It keeps running until the end of the billing period that covers the effective month.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body dtos.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/cancel [post]
+func (h *SubscriptionHandler) Cancel(c echo.Context) error {
+	return h.changeStatus(c, h.service.Cancel)
+}
+
+// ReactivateSubscription godoc
+// @Summary Reactivate subscription
+// @Description Undo a cancellation, restoring the end date the subscription had before it
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body dtos.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/reactivate [post]
+func (h *SubscriptionHandler) Reactivate(c echo.Context) error {
+	return h.changeStatus(c, h.service.Reactivate)
+}
+
+// SubscriptionHistory godoc
+// @Summary Subscription status history
+// @Description List the lifecycle transitions of a subscription, oldest first
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Success 200 {array} models.SubscriptionStatusChange
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/history [get]
+func (h *SubscriptionHandler) History(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	history, err := h.service.History(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+// SubscriptionPrices godoc
+// @Summary Subscription price timeline
+// @Description List the prices of a subscription with the months each one applies to
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Success 200 {array} dtos.PriceTimelineEntry
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/prices [get]
+func (h *SubscriptionHandler) Prices(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	timeline, err := h.service.Prices(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, timeline)
+}
+
+// SchedulePrice godoc
+// @Summary Schedule price change
+// @Description Change the price of a subscription from a given month. Totals for earlier months keep using the previous price.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param price body dtos.SchedulePriceRequest true "New price and the month it applies from"
+// @Success 200 {array} dtos.PriceTimelineEntry
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/{id}/prices [post]
+func (h *SubscriptionHandler) SchedulePrice(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	var req dtos.SchedulePriceRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	timeline, err := h.service.SchedulePrice(id, req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, timeline)
+}
+
+func (h *SubscriptionHandler) changeStatus(
+	c echo.Context,
+	action func(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error),
+) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	var req dtos.StatusChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	sub, err := action(id, req)
+	if err != nil {
+		return err
+	}
+
+	return jsonWithETag(c, http.StatusOK, sub)
+}
+
 // GetTotalCost godoc
 // @Summary Get total cost
-// @Description Calculate total cost of subscriptions for a period
+// @Description Calculate total cost of subscriptions for a period. Every charge of a subscription that falls inside the period is counted according to its billing period and converted at the exchange rate for its month. Amounts are in minor units. CSV, NDJSON and XLSX output holds the breakdown by subscription or by month.
 // @Tags subscriptions
 // @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param start_date query string true "Start date (MM-YYYY)"
 // @Param end_date query string true "End date (MM-YYYY)"
 // @Param user_id query string false "User ID (UUID)"
 // @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param price_min query int false "Minimum price in minor units"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param active_at query string false "Only subscriptions running in this month (MM-YYYY)"
+// @Param start_from query string false "Earliest start month (MM-YYYY)"
+// @Param start_to query string false "Latest start month (MM-YYYY)"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param currency query string false "Currency to report in (ISO 4217, defaults to DEFAULT_CURRENCY)"
+// @Param breakdown query bool false "Include per-month and per-subscription amounts"
+// @Param format query string false "Output format: json, csv, ndjson or xlsx; overrides the Accept header"
+// @Param rows query string false "Rows of csv, ndjson and xlsx output: subscriptions (default) or months"
+// @Param columns query string false "Comma-separated columns of csv, ndjson and xlsx output, in order: subscription_id, service_name, price, currency, billing_period, charges, months, total for subscriptions; month, amount for months"
 // @Success 200 {object} dtos.TotalCostResponse
-// @Failure 400 {object} dtos.ErrorResponse
-// @Failure 500 {object} dtos.ErrorResponse
-// @Router /api/subs/total [get]
+// @Failure 400 {object} apperr.Problem
+// @Failure 406 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/total [get]
 func (h *SubscriptionHandler) TotalCost(c echo.Context) error {
-	startDate := c.QueryParam("start_date")
-	endDate := c.QueryParam("end_date")
+	var period dtos.TotalCostPeriod
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &period); err != nil {
+		return err
+	}
+	if err := c.Validate(period); err != nil {
+		return err
+	}
+	// Both months were checked by the validator.
+	startDate, _ := models.ParseYearMonth(period.StartDate)
+	endDate, _ := models.ParseYearMonth(period.EndDate)
+
+	filter, err := parseFilter(c, models.ParseYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	var currency models.Currency
+	if c.QueryParam("currency") != "" {
+		if currency, err = models.ParseCurrency(c.QueryParam("currency")); err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+	}
+
+	format, err := negotiateFormat(c)
+	if err != nil {
+		return err
+	}
 
-	if startDate == "" || endDate == "" {
-		return c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error: "start_date and end_date are required",
-		})
+	breakdown, _ := strconv.ParseBool(c.QueryParam("breakdown"))
+	if format != export.JSON {
+		breakdown = true
 	}
 
-	resp, err := h.service.GetTotalCost(startDate, endDate, c.QueryParam("user_id"), c.QueryParam("service_name"))
+	resp, err := h.service.GetTotalCost(startDate, endDate, filter, currency, breakdown)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return err
 	}
 
+	if format != export.JSON {
+		return exportTotalCost(c, format, resp)
+	}
 	return c.JSON(http.StatusOK, resp)
 }
+
+// TrialsEnding godoc
+// @Summary List trials ending soon
+// @Description List subscriptions whose trial converts to paid within the given time, soonest first, so they can be cancelled in time
+// @Tags subscriptions
+// @Produce json
+// @Param within query string false "Look-ahead window such as 30d, 2w or 72h (default 30d)"
+// @Param user_id query string false "User ID (UUID)"
+// @Success 200 {array} dtos.TrialEndingItem
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v1/subs/trials/ending [get]
+func (h *SubscriptionHandler) TrialsEnding(c echo.Context) error {
+	within := defaultTrialWindow
+	if value := c.QueryParam("within"); value != "" {
+		var err error
+		if within, err = parseWindow(value); err != nil {
+			return apperr.BadRequest("invalid within, expected e.g. 30d, 2w or 72h")
+		}
+	}
+
+	userID := c.QueryParam("user_id")
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return apperr.BadRequest("invalid user_id")
+		}
+	}
+
+	items, err := h.service.TrialsEnding(within, userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, items)
+}
+
+const defaultTrialWindow = 30 * 24 * time.Hour
+
+// parseWindow accepts a number of days ("30d") or weeks ("2w") besides the
+// units understood by time.ParseDuration.
+func parseWindow(value string) (time.Duration, error) {
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.ParseDuration(value)
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid window %q", value)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	v2 "github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos/v2"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
+)
+
+// SubscriptionV2Handler serves API v2. It takes and returns the bodies of
+// package v2, which use snake_case fields and YYYY-MM months, and maps them
+// onto the same service as v1.
+type SubscriptionV2Handler struct {
+	service        service.SubscriptionService
+	requireIfMatch bool
+}
+
+func NewSubscriptionV2Handler(service service.SubscriptionService, requireIfMatch bool) *SubscriptionV2Handler {
+	return &SubscriptionV2Handler{service: service, requireIfMatch: requireIfMatch}
+}
+
+// CreateSubscriptionV2 godoc
+// @Summary Create subscription
+// @Description Create a new subscription. Depending on the duplicate guard, an overlapping subscription of the user to the same service is rejected with 409 or reported in Warning and Link headers.
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param subscription body v2.CreateSubscriptionRequest true "Subscription data"
+// @Param Idempotency-Key header string false "Unique key of this request; retries with the same key and body return the original response"
+// @Success 201 {object} v2.Subscription
+// @Header 201 {string} ETag "Version of the subscription"
+// @Header 201 {string} Warning "Likely duplicate subscription, with the guard in warn mode"
+// @Failure 400 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs [post]
+func (h *SubscriptionV2Handler) Create(c echo.Context) error {
+	var req v2.CreateSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	sub, duplicates, err := h.service.Create(req.V1())
+	if err != nil {
+		return err
+	}
+
+	warnDuplicates(c, duplicates, "/api/v2/subs")
+	return v2JSONWithETag(c, http.StatusCreated, sub)
+}
+
+// GetSubscriptionV2 godoc
+// @Summary Get subscription
+// @Description Get subscription by ID
+// @Tags subscriptions-v2
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param If-None-Match header string false "ETag of a cached copy; 304 is returned while it is current"
+// @Success 200 {object} v2.Subscription
+// @Success 304
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id} [get]
+func (h *SubscriptionV2Handler) Get(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	sub, err := h.service.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if notModified(c, sub) {
+		c.Response().Header().Set("ETag", etag(sub))
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return v2JSONWithETag(c, http.StatusOK, sub)
+}
+
+// ListSubscriptionsV2 godoc
+// @Summary List subscriptions
+// @Tags subscriptions-v2
+// @Produce json
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param price_min query int false "Minimum price in minor units"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param active_at query string false "Only subscriptions running in this month (YYYY-MM)"
+// @Param start_from query string false "Earliest start month (YYYY-MM)"
+// @Param start_to query string false "Latest start month (YYYY-MM)"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param sort query string false "Comma-separated sort fields, - for descending: service_name, price, currency, status, start_date, end_date, created_at, updated_at (default -created_at)"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset, ignored when cursor is given"
+// @Param cursor query string false "Opaque cursor from meta.next or meta.prev of a previous page"
+// @Param include_total query bool false "Count matching rows (default true with offset, false with cursor)"
+// @Success 200 {object} v2.SubscriptionList
+// @Failure 400 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs [get]
+func (h *SubscriptionV2Handler) List(c echo.Context) error {
+	filter, err := parseFilter(c, models.ParseISOYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	page := dtos.PageRequest{Cursor: c.QueryParam("cursor")}
+	page.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	page.Offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	if value := c.QueryParam("include_total"); value != "" {
+		includeTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return apperr.BadRequest("invalid include_total, expected true or false")
+		}
+		page.IncludeTotal = &includeTotal
+	}
+
+	subs, meta, err := h.service.List(filter, page)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, v2.NewSubscriptionList(subs, meta))
+}
+
+// UpdateSubscriptionV2 godoc
+// @Summary Update subscription
+// @Description Update subscription by ID. A new price applies from price_effective_from, the current month by default; earlier months keep their price.
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param subscription body v2.UpdateSubscriptionRequest true "Subscription data"
+// @Param If-Match header string false "ETag the update is based on"
+// @Success 200 {object} v2.Subscription
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 428 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id} [put]
+func (h *SubscriptionV2Handler) Update(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	ifMatch, sent := ifMatchVersions(c)
+	if !sent && h.requireIfMatch {
+		return errIfMatchRequired
+	}
+
+	var req v2.UpdateSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	sub, err := h.service.Update(id, req.V1(), ifMatch)
+	if err != nil {
+		return err
+	}
+
+	return v2JSONWithETag(c, http.StatusOK, sub)
+}
+
+// DeleteSubscriptionV2 godoc
+// @Summary Delete subscription
+// @Description Move subscription to the trash. It can be restored until it is purged after the retention period.
+// @Tags subscriptions-v2
+// @Param id path string true "Subscription ID (UUID)"
+// @Param If-Match header string false "ETag the deletion is based on"
+// @Success 204
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 412 {object} apperr.Problem
+// @Failure 428 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id} [delete]
+func (h *SubscriptionV2Handler) Delete(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	ifMatch, sent := ifMatchVersions(c)
+	if !sent && h.requireIfMatch {
+		return errIfMatchRequired
+	}
+
+	if err := h.service.Delete(id, ifMatch); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PauseSubscriptionV2 godoc
+// @Summary Pause subscription
+// @Description Pause an active subscription. Charges due while paused are not counted.
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body v2.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} v2.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id}/pause [post]
+func (h *SubscriptionV2Handler) Pause(c echo.Context) error {
+	return h.changeStatus(c, h.service.Pause)
+}
+
+// ResumeSubscriptionV2 godoc
+// @Summary Resume subscription
+// @Description Resume a paused subscription
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body v2.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} v2.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id}/resume [post]
+func (h *SubscriptionV2Handler) Resume(c echo.Context) error {
+	return h.changeStatus(c, h.service.Resume)
+}
+
+// CancelSubscriptionV2 godoc
+// @Summary Cancel subscription
+// @Description Cancel a subscription. It keeps running until the end of the billing period that covers the effective month.
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body v2.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} v2.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id}/cancel [post]
+func (h *SubscriptionV2Handler) Cancel(c echo.Context) error {
+	return h.changeStatus(c, h.service.Cancel)
+}
+
+// ReactivateSubscriptionV2 godoc
+// @Summary Reactivate subscription
+// @Description Undo a cancellation, restoring the end date the subscription had before it
+// @Tags subscriptions-v2
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param change body v2.StatusChangeRequest false "Effective month and reason"
+// @Success 200 {object} v2.Subscription
+// @Failure 400 {object} apperr.Problem
+// @Failure 404 {object} apperr.Problem
+// @Failure 409 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/{id}/reactivate [post]
+func (h *SubscriptionV2Handler) Reactivate(c echo.Context) error {
+	return h.changeStatus(c, h.service.Reactivate)
+}
+
+func (h *SubscriptionV2Handler) changeStatus(
+	c echo.Context,
+	action func(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error),
+) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return apperr.BadRequest("invalid id")
+	}
+
+	var req v2.StatusChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return err
+	}
+
+	sub, err := action(id, req.V1())
+	if err != nil {
+		return err
+	}
+
+	return v2JSONWithETag(c, http.StatusOK, sub)
+}
+
+// GetTotalCostV2 godoc
+// @Summary Get total cost
+// @Description Calculate total cost of subscriptions for a period. Every charge of a subscription that falls inside the period is counted according to its billing period and converted at the exchange rate for its month. Amounts are in minor units.
+// @Tags subscriptions-v2
+// @Produce json
+// @Param start_date query string true "Start month (YYYY-MM)"
+// @Param end_date query string true "End month (YYYY-MM)"
+// @Param user_id query string false "User ID (UUID)"
+// @Param service_name query string false "Service name"
+// @Param name_match query string false "How service_name matches, ignoring case: contains (default) or exact"
+// @Param category query string false "Category, ignoring case"
+// @Param status query string false "Status (trial, active, paused, cancelled)"
+// @Param price_min query int false "Minimum price in minor units"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param active_at query string false "Only subscriptions running in this month (YYYY-MM)"
+// @Param start_from query string false "Earliest start month (YYYY-MM)"
+// @Param start_to query string false "Latest start month (YYYY-MM)"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param currency query string false "Currency to report in (ISO 4217, defaults to DEFAULT_CURRENCY)"
+// @Param breakdown query bool false "Include per-month and per-subscription amounts"
+// @Success 200 {object} v2.TotalCost
+// @Failure 400 {object} apperr.Problem
+// @Failure 422 {object} apperr.Problem
+// @Failure 500 {object} apperr.Problem
+// @Router /api/v2/subs/total [get]
+func (h *SubscriptionV2Handler) TotalCost(c echo.Context) error {
+	var period v2.TotalCostPeriod
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &period); err != nil {
+		return err
+	}
+	if err := c.Validate(period); err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(c, models.ParseISOYearMonth)
+	if err != nil {
+		return apperr.BadRequest(err.Error())
+	}
+
+	var currency models.Currency
+	if c.QueryParam("currency") != "" {
+		if currency, err = models.ParseCurrency(c.QueryParam("currency")); err != nil {
+			return apperr.BadRequest(err.Error())
+		}
+	}
+
+	breakdown, _ := strconv.ParseBool(c.QueryParam("breakdown"))
+
+	resp, err := h.service.GetTotalCost(period.StartDate.YearMonth(), period.EndDate.YearMonth(), filter, currency, breakdown)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, v2.NewTotalCost(resp))
+}
+
+// v2JSONWithETag writes sub as a v2 body along with its ETag header.
+func v2JSONWithETag(c echo.Context, status int, sub *models.Subscription) error {
+	c.Response().Header().Set("ETag", etag(sub))
+	return c.JSON(status, v2.NewSubscription(sub))
+}
@@ -1,22 +1,120 @@
 package http
 
 import (
+	"context"
+	"log"
+
+	"github.com/labstack/echo/v4"
+
 	"github.com/Ilmyrat1822/subs/cmd"
+	"github.com/Ilmyrat1822/subs/internal/deprecation"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/handler"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/service"
 )
 
-func InitSubscriptionRouter(server *cmd.Server) {
+// InitSubscriptionRouter registers the subscription endpoints and returns the
+// service so other modules can calculate spend with it.
+func InitSubscriptionRouter(server *cmd.Server, rates service.ExchangeRates, idempotent echo.MiddlewareFunc, legacy deprecation.Window) service.SubscriptionService {
 	subsRepository := repository.NewSubscriptionRepository(server.Database)
-	subsService := service.NewSubscriptionService(subsRepository)
-	subsHandler := handler.NewSubscriptionHandler(subsService)
+	subsService := service.NewSubscriptionService(
+		subsRepository,
+		rates,
+		server.Config.DefaultCurrency,
+		server.Config.TrashRetention,
+		duplicateGuard(server.Config.DuplicateGuard),
+	)
+	subsHandler := handler.NewSubscriptionHandler(
+		subsService,
+		server.Config.RequireIfMatch,
+		server.Config.BulkMaxOperations,
+	)
+
+	server.Jobs.Every("trial-conversion", server.Config.TrialConversionInterval, func(ctx context.Context) error {
+		_, err := subsService.ConvertEndedTrials()
+		return err
+	})
+	server.Jobs.Every("trash-purge", server.Config.TrashPurgeInterval, func(ctx context.Context) error {
+		_, err := subsService.PurgeTrash()
+		return err
+	})
+
+	v2Handler := handler.NewSubscriptionV2Handler(subsService, server.Config.RequireIfMatch)
+	registerRoutes(server.Echo, subsHandler, v2Handler, idempotent, legacy)
+
+	return subsService
+}
 
-	subsRouter := server.Echo.Group("/api/subs")
+// registerRoutes adds the v1 and v2 routes and the deprecated unversioned
+// aliases of v1.
+func registerRoutes(
+	e *echo.Echo,
+	subsHandler *handler.SubscriptionHandler,
+	v2Handler *handler.SubscriptionV2Handler,
+	idempotent echo.MiddlewareFunc,
+	legacy deprecation.Window,
+) {
+	// The unversioned routes are v1 under their old path, kept until the
+	// sunset for clients that have not moved to /api/v1 yet. Only the routes
+	// that v2 also serves get a sunset; the others are deprecated without a
+	// removal date until v2 covers them.
+	v1Router := e.Group("/api/v1/subs")
+	registerV1(v1Router, v1Router, subsHandler, idempotent)
+	registerV1(
+		e.Group("/api/subs", legacy.Middleware("/api/subs", "/api/v1/subs")),
+		e.Group("/api/subs", legacy.WithoutSunset().Middleware("/api/subs", "/api/v1/subs")),
+		subsHandler,
+		idempotent,
+	)
+
+	v2Router := e.Group("/api/v2/subs")
+	v2Router.GET("", v2Handler.List)
+	v2Router.POST("", v2Handler.Create, idempotent)
+	v2Router.GET("/total", v2Handler.TotalCost)
+	v2Router.GET("/:id", v2Handler.Get)
+	v2Router.PUT("/:id", v2Handler.Update)
+	v2Router.DELETE("/:id", v2Handler.Delete)
+	v2Router.POST("/:id/pause", v2Handler.Pause)
+	v2Router.POST("/:id/resume", v2Handler.Resume)
+	v2Router.POST("/:id/cancel", v2Handler.Cancel)
+	v2Router.POST("/:id/reactivate", v2Handler.Reactivate)
+}
+
+// registerV1 adds the v1 routes that v2 also serves to subsRouter and the
+// others to v1OnlyRouter.
+func registerV1(subsRouter, v1OnlyRouter *echo.Group, subsHandler *handler.SubscriptionHandler, idempotent echo.MiddlewareFunc) {
 	subsRouter.GET("/list", subsHandler.List)
-	subsRouter.POST("", subsHandler.Create)
+	subsRouter.POST("", subsHandler.Create, idempotent)
 	subsRouter.GET("/total", subsHandler.TotalCost)
 	subsRouter.GET("/:id", subsHandler.Get)
 	subsRouter.PUT("/:id", subsHandler.Update)
 	subsRouter.DELETE("/:id", subsHandler.Delete)
+	subsRouter.POST("/:id/pause", subsHandler.Pause)
+	subsRouter.POST("/:id/resume", subsHandler.Resume)
+	subsRouter.POST("/:id/cancel", subsHandler.Cancel)
+	subsRouter.POST("/:id/reactivate", subsHandler.Reactivate)
+
+	v1OnlyRouter.POST("/bulk", subsHandler.Bulk, idempotent)
+	v1OnlyRouter.GET("/trials/ending", subsHandler.TrialsEnding)
+	v1OnlyRouter.GET("/duplicates", subsHandler.Duplicates)
+	v1OnlyRouter.GET("/analytics/monthly", subsHandler.MonthlySpending)
+	v1OnlyRouter.GET("/analytics/breakdown", subsHandler.SpendingBreakdown)
+	v1OnlyRouter.GET("/analytics/forecast", subsHandler.SpendingForecast)
+	v1OnlyRouter.GET("/trash", subsHandler.Trash)
+	v1OnlyRouter.POST("/trash/purge", subsHandler.PurgeTrash)
+	v1OnlyRouter.PATCH("/:id", subsHandler.Patch)
+	v1OnlyRouter.POST("/:id/restore", subsHandler.Restore)
+	v1OnlyRouter.GET("/:id/history", subsHandler.History)
+	v1OnlyRouter.GET("/:id/prices", subsHandler.Prices)
+	v1OnlyRouter.POST("/:id/prices", subsHandler.SchedulePrice)
+}
+
+func duplicateGuard(mode string) string {
+	switch mode {
+	case dtos.DuplicateGuardOff, dtos.DuplicateGuardWarn, dtos.DuplicateGuardReject:
+		return mode
+	}
+	log.Fatalf("invalid DUPLICATE_GUARD %q, expected off, warn or reject", mode)
+	return ""
 }
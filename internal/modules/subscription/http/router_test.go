@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/Ilmyrat1822/subs/internal/deprecation"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/handler"
+)
+
+// newRoutes registers the routes on a fresh Echo. The handlers have no
+// service: a request that gets as far as calling it panics, which Recover
+// answers with a 500, after the deprecation headers have been set.
+func newRoutes() *echo.Echo {
+	e := echo.New()
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error { return err },
+	}))
+	passthrough := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	legacy := deprecation.Window{
+		DeprecatedAt: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	registerRoutes(e, handler.NewSubscriptionHandler(nil, false, 100), handler.NewSubscriptionV2Handler(nil, false), passthrough, legacy)
+	return e
+}
+
+func serve(e *echo.Echo, method, path string) http.Header {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec.Header()
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	e := newRoutes()
+
+	v1Routes := map[string]bool{}
+	v2Routes := map[string]bool{}
+	var legacyRoutes []*echo.Route
+	for _, route := range e.Routes() {
+		switch {
+		case route.Method == echo.RouteNotFound:
+			// Added by groups with middleware.
+		case strings.HasPrefix(route.Path, "/api/v1/subs"):
+			v1Routes[route.Method+" "+strings.TrimPrefix(route.Path, "/api/v1/subs")] = true
+		case strings.HasPrefix(route.Path, "/api/v2/subs"):
+			v2Routes[route.Method+" "+strings.TrimPrefix(route.Path, "/api/v2/subs")] = true
+		case strings.HasPrefix(route.Path, "/api/subs"):
+			legacyRoutes = append(legacyRoutes, route)
+		}
+	}
+	if len(legacyRoutes) != len(v1Routes) {
+		t.Errorf("%d legacy routes for %d v1 routes", len(legacyRoutes), len(v1Routes))
+	}
+
+	for _, route := range legacyRoutes {
+		rest := strings.TrimPrefix(route.Path, "/api/subs")
+		if !v1Routes[route.Method+" "+rest] {
+			t.Errorf("%s %s has no v1 route", route.Method, route.Path)
+		}
+		// The v1 list is the collection itself in v2.
+		v2Path := strings.TrimPrefix(rest, "/list")
+		wantSunset := v2Routes[route.Method+" "+v2Path]
+
+		path := strings.ReplaceAll(route.Path, ":id", "2f1c3f1e-8c5b-4d6e-9f7a-0b1c2d3e4f50")
+		header := serve(e, route.Method, path)
+		if got := header.Get(deprecation.HeaderDeprecation); got != "@1751328000" {
+			t.Errorf("%s %s Deprecation = %q, want @1751328000", route.Method, path, got)
+		}
+		wantLink := "</api/v1/subs" + strings.TrimPrefix(path, "/api/subs") + `>; rel="successor-version"`
+		if got := header.Get("Link"); got != wantLink {
+			t.Errorf("%s %s Link = %q, want %q", route.Method, path, got, wantLink)
+		}
+		if got := header.Get(deprecation.HeaderSunset); (got != "") != wantSunset {
+			t.Errorf("%s %s Sunset = %q, want one: %v", route.Method, path, got, wantSunset)
+		}
+	}
+}
+
+func TestVersionedRoutesAreNotDeprecated(t *testing.T) {
+	e := newRoutes()
+	for _, path := range []string{"/api/v1/subs/not-an-id", "/api/v1/subs/not-an-id/history", "/api/v2/subs/not-an-id"} {
+		header := serve(e, http.MethodGet, path)
+		for _, name := range []string{deprecation.HeaderDeprecation, deprecation.HeaderSunset, "Link"} {
+			if got := header.Get(name); got != "" {
+				t.Errorf("GET %s %s = %q, want none", path, name, got)
+			}
+		}
+	}
+}
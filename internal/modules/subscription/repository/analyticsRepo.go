@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// MonthActivity summarizes the subscriptions of one currency in one month.
+// Active counts the subscriptions running and not paused in Month, Started
+// those whose start month it is and Ended those whose end month it is.
+// Amount is the sum of the charges due in Month, in minor units of Currency.
+type MonthActivity struct {
+	Month    models.YearMonth
+	Currency models.Currency
+	Active   int64
+	Started  int64
+	Ended    int64
+	Amount   int64
+}
+
+// filteredSubscriptions selects the subscriptions matching the filter along
+// with the first month they are charged for and the months between charges,
+// 0 for weekly billing, mirroring Subscription.BillingStart and
+// BillingPeriod.IntervalMonths.
+const filteredSubscriptions = `id, currency, billing_period, start_date, end_date, price,
+	CASE WHEN trial_end IS NOT NULL AND trial_end >= start_date
+		THEN (trial_end + INTERVAL '1 month')::date
+		ELSE start_date
+	END AS billing_start,
+	CASE billing_period
+		WHEN 'weekly' THEN 0
+		WHEN 'quarterly' THEN 3
+		WHEN 'yearly' THEN 12
+		ELSE COALESCE(substring(billing_period FROM '^every-([0-9]+)-months$')::int, 1)
+	END AS interval_months`
+
+// monthlyActivityQuery joins every month of the series with the subscriptions
+// running in it and works out, per pair, whether it is paused, how many
+// charges fall in the month and the price in force, following the rules of
+// the total cost calculation: weekly charges repeat every seven days from the
+// billing start, other periods charge in the billing start month and every
+// interval_months after it, and charges due while paused are skipped.
+const monthlyActivityQuery = `
+WITH months AS (
+	SELECT generate_series(?::date, ?::date, INTERVAL '1 month')::date AS month
+)
+SELECT
+	m.month,
+	s.currency,
+	COUNT(*) FILTER (WHERE NOT c.paused) AS active,
+	COUNT(*) FILTER (WHERE s.start_date = m.month) AS started,
+	COUNT(*) FILTER (WHERE s.end_date = m.month) AS ended,
+	COALESCE(SUM(c.charges * c.price) FILTER (WHERE NOT c.paused), 0)::bigint AS amount
+FROM months m
+JOIN (?) s ON s.start_date <= m.month AND (s.end_date IS NULL OR s.end_date >= m.month)
+CROSS JOIN LATERAL (
+	SELECT
+		EXISTS (
+			SELECT 1 FROM subscription_pauses p
+			WHERE p.subscription_id = s.id
+				AND p.paused_from <= m.month
+				AND (p.resumed_from IS NULL OR p.resumed_from > m.month)
+		) AS paused,
+		CASE
+			WHEN s.interval_months = 0 THEN GREATEST(0,
+				FLOOR(((m.month + INTERVAL '1 month')::date - 1 - s.billing_start) / 7.0)
+				- CEIL((GREATEST(m.month, s.billing_start) - s.billing_start) / 7.0)
+				+ 1)
+			WHEN m.month >= s.billing_start
+				AND ((EXTRACT(YEAR FROM m.month) - EXTRACT(YEAR FROM s.billing_start)) * 12
+					+ EXTRACT(MONTH FROM m.month) - EXTRACT(MONTH FROM s.billing_start))::int
+					% s.interval_months = 0
+				THEN 1
+			ELSE 0
+		END AS charges,
+		COALESCE(
+			(SELECT p.price FROM subscription_prices p
+				WHERE p.subscription_id = s.id AND p.effective_from <= m.month
+				ORDER BY p.effective_from DESC LIMIT 1),
+			(SELECT p.price FROM subscription_prices p
+				WHERE p.subscription_id = s.id
+				ORDER BY p.effective_from LIMIT 1),
+			s.price
+		) AS price
+) c
+GROUP BY m.month, s.currency
+ORDER BY m.month, s.currency`
+
+// MonthlyActivity computes, in the database, one MonthActivity per month of
+// the inclusive [from, to] window and currency of the subscriptions matching
+// filter. Months without any running subscription have no rows.
+func (r *subscriptionRepository) MonthlyActivity(from, to models.YearMonth, filter dtos.SubscriptionFilter) ([]MonthActivity, error) {
+	subs := applyFilter(r.db.Model(&models.Subscription{}), filter).Select(filteredSubscriptions)
+
+	var rows []MonthActivity
+	err := r.db.Raw(monthlyActivityQuery, from, to, subs).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// defaultSort is the order used when the caller does not ask for one.
+var defaultSort = []dtos.SortField{{Field: "created_at", Desc: true}}
+
+// noEndDate stands in for a NULL end_date so that open-ended subscriptions
+// sort last and can be compared in keyset conditions.
+var noEndDate = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+type sortKey struct {
+	// expr is the SQL expression ordered by; it never evaluates to NULL.
+	expr  string
+	value func(sub models.Subscription) any
+	// decode restores a value after a JSON round trip.
+	decode func(raw json.RawMessage) (any, error)
+}
+
+var sortKeys = map[string]sortKey{
+	"service_name": {expr: "service_name", value: func(s models.Subscription) any { return s.ServiceName }, decode: decodeAs[string]},
+	"price": {
+		expr: currentPrice,
+		value: func(s models.Subscription) any {
+			return s.PriceIn(models.CurrentMonth())
+		},
+		decode: decodeAs[int64],
+	},
+	"currency":   {expr: "currency", value: func(s models.Subscription) any { return string(s.Currency) }, decode: decodeAs[string]},
+	"status":     {expr: "status", value: func(s models.Subscription) any { return string(s.Status) }, decode: decodeAs[string]},
+	"start_date": {expr: "start_date", value: func(s models.Subscription) any { return s.StartDate.Time() }, decode: decodeAs[time.Time]},
+	"end_date": {
+		expr: "COALESCE(end_date, DATE '9999-12-31')",
+		value: func(s models.Subscription) any {
+			if s.EndDate == nil {
+				return noEndDate
+			}
+			return s.EndDate.Time()
+		},
+		decode: decodeAs[time.Time],
+	},
+	"created_at": {expr: "created_at", value: func(s models.Subscription) any { return s.CreatedAt }, decode: decodeAs[time.Time]},
+	"updated_at": {expr: "updated_at", value: func(s models.Subscription) any { return s.UpdatedAt }, decode: decodeAs[time.Time]},
+}
+
+func decodeAs[T any](raw json.RawMessage) (any, error) {
+	var v T
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// Cursor is a position in a sorted listing: the sort key values and id of a
+// row. Backward cursors select the rows before that row instead of after it.
+type Cursor struct {
+	Sort     string
+	Values   []any
+	ID       uuid.UUID
+	Backward bool
+}
+
+type encodedCursor struct {
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+	ID       uuid.UUID         `json:"id"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// CursorAt returns the cursor positioned on sub in a listing ordered by fields.
+func CursorAt(sub models.Subscription, fields []dtos.SortField, backward bool) Cursor {
+	fields = sortOrDefault(fields)
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		values[i] = sortKeys[field.Field].value(sub)
+	}
+	return Cursor{Sort: sortSignature(fields), Values: values, ID: sub.ID, Backward: backward}
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c Cursor) Encode() string {
+	enc := encodedCursor{Sort: c.Sort, ID: c.ID, Backward: c.Backward}
+	for _, value := range c.Values {
+		raw, _ := json.Marshal(value)
+		enc.Values = append(enc.Values, raw)
+	}
+	data, _ := json.Marshal(enc)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor made by Encode and checks that it belongs to a
+// listing ordered by fields.
+func DecodeCursor(value string, fields []dtos.SortField) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var enc encodedCursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&enc); err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	fields = sortOrDefault(fields)
+	if enc.Sort != sortSignature(fields) || len(enc.Values) != len(fields) {
+		return nil, errors.New("cursor was issued for a different sort")
+	}
+
+	cursor := &Cursor{Sort: enc.Sort, ID: enc.ID, Backward: enc.Backward}
+	for i, field := range fields {
+		v, err := sortKeys[field.Field].decode(enc.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("malformed cursor value for %s", field.Field)
+		}
+		cursor.Values = append(cursor.Values, v)
+	}
+	return cursor, nil
+}
+
+func sortOrDefault(fields []dtos.SortField) []dtos.SortField {
+	if len(fields) == 0 {
+		return defaultSort
+	}
+	return fields
+}
+
+func sortSignature(fields []dtos.SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// applySort orders query by fields, newest first when there are none, with
+// the id as tie-breaker so that the order is total. When reverse is set the
+// whole order is inverted, which is how backward pages are read.
+func applySort(query *gorm.DB, fields []dtos.SortField, reverse bool) *gorm.DB {
+	for _, field := range sortOrDefault(fields) {
+		query = query.Order(orderTerm(sortKeys[field.Field].expr, field.Desc != reverse))
+	}
+	return query.Order(orderTerm("id", reverse))
+}
+
+func orderTerm(expr string, desc bool) string {
+	if desc {
+		return expr + " DESC"
+	}
+	return expr + " ASC"
+}
+
+// applyCursor restricts query to the rows after cursor in the order given by
+// fields, or before it for backward cursors. For fields a, b it builds
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?), with the
+// comparisons flipped for descending fields.
+func applyCursor(query *gorm.DB, fields []dtos.SortField, cursor Cursor) *gorm.DB {
+	fields = sortOrDefault(fields)
+
+	exprs := make([]string, 0, len(fields)+1)
+	values := make([]any, 0, len(fields)+1)
+	descs := make([]bool, 0, len(fields)+1)
+	for i, field := range fields {
+		exprs = append(exprs, sortKeys[field.Field].expr)
+		values = append(values, cursor.Values[i])
+		descs = append(descs, field.Desc)
+	}
+	exprs = append(exprs, "id")
+	values = append(values, cursor.ID)
+	descs = append(descs, false)
+
+	var terms []string
+	var args []any
+	for i := range exprs {
+		op := ">"
+		if descs[i] != cursor.Backward {
+			op = "<"
+		}
+		conds := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, exprs[j]+" = ?")
+			args = append(args, values[j])
+		}
+		conds = append(conds, exprs[i]+" "+op+" ?")
+		args = append(args, values[i])
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+
+	return query.Where("("+strings.Join(terms, " OR ")+")", args...)
+}
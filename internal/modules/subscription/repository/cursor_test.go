@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2025, time.July, 17, 9, 30, 0, 123456000, time.UTC)
+	end := models.NewYearMonth(2025, time.December)
+	sub := models.Subscription{
+		ID:          uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba"),
+		ServiceName: "Yandex Plus",
+		Price:       39900,
+		Currency:    "RUB",
+		Status:      models.StatusActive,
+		StartDate:   models.NewYearMonth(2025, time.July),
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+	ended := sub
+	ended.EndDate = &end
+
+	tests := []struct {
+		name       string
+		sub        models.Subscription
+		fields     []dtos.SortField
+		backward   bool
+		wantSort   string
+		wantValues []any
+	}{
+		{
+			name:       "default sort",
+			sub:        sub,
+			wantSort:   "-created_at",
+			wantValues: []any{created},
+		},
+		{
+			name:       "backward",
+			sub:        sub,
+			backward:   true,
+			wantSort:   "-created_at",
+			wantValues: []any{created},
+		},
+		{
+			name:       "several fields",
+			sub:        sub,
+			fields:     []dtos.SortField{{Field: "service_name"}, {Field: "price", Desc: true}, {Field: "start_date"}},
+			wantSort:   "service_name,-price,start_date",
+			wantValues: []any{"Yandex Plus", int64(39900), sub.StartDate.Time()},
+		},
+		{
+			name:       "open end date sorts last",
+			sub:        sub,
+			fields:     []dtos.SortField{{Field: "end_date"}},
+			wantSort:   "end_date",
+			wantValues: []any{noEndDate},
+		},
+		{
+			name:       "end date",
+			sub:        ended,
+			fields:     []dtos.SortField{{Field: "end_date"}},
+			wantSort:   "end_date",
+			wantValues: []any{end.Time()},
+		},
+		{
+			name:       "strings",
+			sub:        sub,
+			fields:     []dtos.SortField{{Field: "currency"}, {Field: "status", Desc: true}},
+			wantSort:   "currency,-status",
+			wantValues: []any{"RUB", string(models.StatusActive)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := CursorAt(tt.sub, tt.fields, tt.backward).Encode()
+			got, err := DecodeCursor(encoded, tt.fields)
+			if err != nil {
+				t.Fatalf("DecodeCursor(%q) error = %v", encoded, err)
+			}
+			want := &Cursor{Sort: tt.wantSort, Values: tt.wantValues, ID: tt.sub.ID, Backward: tt.backward}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	sub := models.Subscription{ID: uuid.New(), ServiceName: "Netflix", CreatedAt: time.Now().UTC()}
+	byName := []dtos.SortField{{Field: "service_name"}}
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		value  string
+		fields []dtos.SortField
+	}{
+		{name: "not base64", value: "not a cursor!"},
+		{name: "not JSON", value: raw("created_at")},
+		{name: "unknown member", value: raw(`{"s":"-created_at","v":["2025-07-01T00:00:00Z"],"id":"` + sub.ID.String() + `","x":1}`)},
+		{name: "other sort", value: CursorAt(sub, nil, false).Encode(), fields: byName},
+		{name: "other direction", value: CursorAt(sub, byName, false).Encode(), fields: []dtos.SortField{{Field: "service_name", Desc: true}}},
+		{name: "missing value", value: raw(`{"s":"service_name","v":[],"id":"` + sub.ID.String() + `"}`), fields: byName},
+		{name: "wrong value type", value: raw(`{"s":"service_name","v":[42],"id":"` + sub.ID.String() + `"}`), fields: byName},
+		{name: "bad time", value: raw(`{"s":"-created_at","v":["yesterday"],"id":"` + sub.ID.String() + `"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeCursor(tt.value, tt.fields); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want an error", tt.value, got)
+			}
+		})
+	}
+}
@@ -1,19 +1,38 @@
 package repository
 
 import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/Ilmyrat1822/subs/internal/models"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
 )
 
 type SubscriptionRepository interface {
+	WithTx(fn func(repo SubscriptionRepository) error) error
 	Create(sub *models.Subscription) error
-	GetByID(id int) (*models.Subscription, error)
-	Update(sub *models.Subscription) (bool, error)
-	Delete(id int) (bool, error)
-	List(userID, serviceName string, limit, offset int) ([]models.Subscription, int64, error)
-	GetTotalCost(startDate, endDate, userID, serviceName string) (*dtos.TotalCostResponse, error)
+	GetByID(id uuid.UUID) (*models.Subscription, error)
+	Update(sub *models.Subscription, newPrice *models.SubscriptionPrice) (bool, error)
+	Delete(id uuid.UUID, version int64) (bool, error)
+	ListDeleted(userID string, limit, offset int) ([]models.Subscription, int64, error)
+	GetDeleted(id uuid.UUID) (*models.Subscription, error)
+	Restore(id uuid.UUID, version int64) (bool, error)
+	Purge(deletedBefore time.Time) (int64, error)
+	List(filter dtos.SubscriptionFilter, limit, offset int) ([]models.Subscription, error)
+	ListAfter(filter dtos.SubscriptionFilter, limit int, cursor Cursor) ([]models.Subscription, error)
+	Count(filter dtos.SubscriptionFilter) (int64, error)
+	Stream(filter dtos.SubscriptionFilter, month models.YearMonth, fn func(sub models.Subscription) error) error
+	StreamByUser(userID string, month models.YearMonth, fn func(sub models.Subscription) error) error
+	ListActiveInRange(startDate, endDate models.YearMonth, filter dtos.SubscriptionFilter) ([]models.Subscription, error)
+	MonthlyActivity(from, to models.YearMonth, filter dtos.SubscriptionFilter) ([]MonthActivity, error)
+	ListTrialsEndingBy(lastTrialMonth models.YearMonth, userID string) ([]models.Subscription, error)
+	Transition(sub *models.Subscription, change *models.SubscriptionStatusChange) (bool, error)
+	History(id uuid.UUID) ([]models.SubscriptionStatusChange, error)
+	Prices(id uuid.UUID) ([]models.SubscriptionPrice, error)
 }
 type subscriptionRepository struct {
 	db *gorm.DB
@@ -27,31 +46,73 @@ func (r *subscriptionRepository) Create(sub *models.Subscription) error {
 	return r.db.Create(sub).Error
 }
 
-func (r *subscriptionRepository) GetByID(id int) (*models.Subscription, error) {
+// WithTx runs fn with a repository bound to a single transaction, which is
+// committed when fn returns nil and rolled back otherwise.
+func (r *subscriptionRepository) WithTx(fn func(repo SubscriptionRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&subscriptionRepository{db: tx})
+	})
+}
+
+func (r *subscriptionRepository) GetByID(id uuid.UUID) (*models.Subscription, error) {
 	var sub models.Subscription
-	err := r.db.First(&sub, id).Error
+	err := withDetails(r.db).First(&sub, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &sub, nil
 }
 
-func (r *subscriptionRepository) Update(sub *models.Subscription) (bool, error) {
-	result := r.db.Save(sub)
+// Update saves sub without its associations, provided nobody changed it
+// since it was read: the row is only written while its version still equals
+// sub.Version, which is then incremented. It reports false when the version
+// no longer matches. A non-nil newPrice is stored in the price history in the
+// same transaction, replacing any change already scheduled for the same
+// month.
+func (r *subscriptionRepository) Update(sub *models.Subscription, newPrice *models.SubscriptionPrice) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := saveVersioned(tx, sub)
+		if err != nil || !ok {
+			return err
+		}
+		updated = true
 
-	if result.Error != nil {
-		return false, result.Error
-	}
+		if newPrice == nil {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "effective_from"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price"}),
+		}).Create(newPrice).Error
+	})
 
-	if result.RowsAffected == 0 {
-		return false, nil
-	}
+	return updated, err
+}
+
+// saveVersioned writes every column of sub with a WHERE on its version and
+// bumps the version on success. Save cannot be used here because it falls
+// back to an upsert when no row matches.
+func saveVersioned(tx *gorm.DB, sub *models.Subscription) (bool, error) {
+	expected := sub.Version
+	sub.Version++
 
+	result := tx.Model(sub).
+		Select("*").
+		Omit("created_at", clause.Associations).
+		Where("version = ?", expected).
+		Updates(sub)
+	if result.Error != nil || result.RowsAffected == 0 {
+		sub.Version = expected
+		return false, result.Error
+	}
 	return true, nil
 }
 
-func (r *subscriptionRepository) Delete(id int) (bool, error) {
-	res := r.db.Delete(&models.Subscription{}, id)
+// Delete moves the subscription to the trash if its version is still
+// version. It reports false when no such subscription exists.
+func (r *subscriptionRepository) Delete(id uuid.UUID, version int64) (bool, error) {
+	res := r.db.Where("version = ?", version).Delete(&models.Subscription{}, "id = ?", id)
 
 	if res.Error != nil {
 		return false, res.Error
@@ -64,27 +125,24 @@ func (r *subscriptionRepository) Delete(id int) (bool, error) {
 	return true, nil
 }
 
-func (r *subscriptionRepository) List(userID, serviceName string, limit, offset int) ([]models.Subscription, int64, error) {
-
+// ListDeleted returns the soft-deleted subscriptions, most recently deleted
+// first.
+func (r *subscriptionRepository) ListDeleted(userID string, limit, offset int) ([]models.Subscription, int64, error) {
 	var subs []models.Subscription
 	var total int64
 
-	query := r.db.Model(&models.Subscription{})
+	query := r.db.Unscoped().Model(&models.Subscription{}).Where("deleted_at IS NOT NULL")
 
 	if userID != "" {
 		query = query.Where("user_id = ?", userID)
 	}
-	if serviceName != "" {
-		query = query.Where("service_name ILIKE ?", "%"+serviceName+"%")
-	}
 
-	// count before limit
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := query.
-		Order("created_at DESC").
+	err := withDetails(query).
+		Order("deleted_at DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&subs).Error
@@ -92,33 +150,291 @@ func (r *subscriptionRepository) List(userID, serviceName string, limit, offset
 	return subs, total, err
 }
 
-type TotalAggResult struct {
-	Total int64
-	Count int64
+// GetDeleted returns the subscription with id if it is in the trash.
+func (r *subscriptionRepository) GetDeleted(id uuid.UUID) (*models.Subscription, error) {
+	var sub models.Subscription
+	err := withDetails(r.db.Unscoped()).First(&sub, "id = ? AND deleted_at IS NOT NULL", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Restore clears deleted_at of a soft-deleted subscription if its version is
+// still version. It reports false when no such subscription is in the trash.
+func (r *subscriptionRepository) Restore(id uuid.UUID, version int64) (bool, error) {
+	res := r.db.Unscoped().
+		Model(&models.Subscription{}).
+		Where("id = ? AND version = ? AND deleted_at IS NOT NULL", id, version).
+		Updates(map[string]any{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// Purge permanently removes the subscriptions deleted before deletedBefore.
+// Their pauses, prices and history go with them through ON DELETE CASCADE.
+func (r *subscriptionRepository) Purge(deletedBefore time.Time) (int64, error) {
+	res := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&models.Subscription{})
+
+	return res.RowsAffected, res.Error
+}
+
+// List returns a page of the subscriptions matching filter by offset.
+func (r *subscriptionRepository) List(filter dtos.SubscriptionFilter, limit, offset int) ([]models.Subscription, error) {
+	var subs []models.Subscription
+
+	query := applyFilter(r.db.Model(&models.Subscription{}), filter)
+
+	err := applySort(withDetails(query), filter.Sort, false).
+		Limit(limit).
+		Offset(offset).
+		Find(&subs).Error
+
+	return subs, err
+}
+
+// ListAfter returns up to limit subscriptions matching filter that follow
+// cursor in the listing order, or precede it for backward cursors. Rows are
+// returned in listing order either way.
+func (r *subscriptionRepository) ListAfter(filter dtos.SubscriptionFilter, limit int, cursor Cursor) ([]models.Subscription, error) {
+	var subs []models.Subscription
+
+	query := applyCursor(applyFilter(r.db.Model(&models.Subscription{}), filter), filter.Sort, cursor)
+
+	err := applySort(withDetails(query), filter.Sort, cursor.Backward).
+		Limit(limit).
+		Find(&subs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor.Backward {
+		slices.Reverse(subs)
+	}
+	return subs, nil
+}
+
+func (r *subscriptionRepository) Count(filter dtos.SubscriptionFilter) (int64, error) {
+	var total int64
+	err := applyFilter(r.db.Model(&models.Subscription{}), filter).Count(&total).Error
+	return total, err
+}
+
+// priceInForce is the SQL for the price in force in month, a date
+// expression, according to the price history. Like
+// models.Subscription.PriceIn it falls back to the earliest entry for months
+// before the history starts, and to the stored price without a history.
+func priceInForce(month string) string {
+	return `COALESCE((
+		SELECT p.price FROM subscription_prices p
+		WHERE p.subscription_id = subscriptions.id AND p.effective_from <= ` + month + `
+		ORDER BY p.effective_from DESC LIMIT 1
+	), (
+		SELECT p.price FROM subscription_prices p
+		WHERE p.subscription_id = subscriptions.id
+		ORDER BY p.effective_from LIMIT 1
+	), subscriptions.price)`
 }
 
-func (r *subscriptionRepository) GetTotalCost(startDate, endDate, userID, serviceName string) (*dtos.TotalCostResponse, error) {
-	var result dtos.TotalCostResponse
+// currentMonthSQL is the first day of the current UTC month, the month
+// whose prices the list shows.
+const currentMonthSQL = "CAST(date_trunc('month', now() AT TIME ZONE 'UTC') AS date)"
+
+// currentPrice is the SQL for the price in force in the current month. The
+// price filters and the price sort use it rather than the stored column,
+// which is not updated when a scheduled price takes effect.
+var currentPrice = priceInForce(currentMonthSQL)
+
+// streamColumns are the columns read by Stream. The price is replaced by the
+// price in force in the requested month, so that the price history does not
+// have to be loaded for every row.
+var streamColumns = `id, service_name, category, currency, billing_period, user_id, start_date, end_date,
+	trial_end, trial_converted_at, status, version, created_at, updated_at, deleted_at,
+	` + priceInForce("?") + ` AS price`
+
+// Stream calls fn for every subscription matching filter, in listing order.
+// Rows are read one at a time from a database cursor, so memory use does not
+// grow with the number of rows. Pauses and prices are not loaded; Price is
+// the price in force in month. Stream stops at the first error of fn.
+func (r *subscriptionRepository) Stream(filter dtos.SubscriptionFilter, month models.YearMonth, fn func(sub models.Subscription) error) error {
+	query := applyFilter(r.db.Model(&models.Subscription{}), filter).Select(streamColumns, month)
+	return r.streamRows(applySort(query, filter.Sort, false), fn)
+}
+
+// StreamByUser is Stream ordered by user and start month, so that the
+// subscriptions of a user arrive together, earliest first. An empty userID
+// streams every user.
+func (r *subscriptionRepository) StreamByUser(userID string, month models.YearMonth, fn func(sub models.Subscription) error) error {
+	query := applyFilter(r.db.Model(&models.Subscription{}), dtos.SubscriptionFilter{UserID: userID}).
+		Select(streamColumns, month).
+		Order("user_id, start_date, id")
+	return r.streamRows(query, fn)
+}
+
+func (r *subscriptionRepository) streamRows(query *gorm.DB, fn func(sub models.Subscription) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sub models.Subscription
+		if err := r.db.ScanRows(rows, &sub); err != nil {
+			return err
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ListActiveInRange returns the subscriptions that are active for at least one
+// month of the inclusive [startDate, endDate] window.
+func (r *subscriptionRepository) ListActiveInRange(startDate, endDate models.YearMonth, filter dtos.SubscriptionFilter) ([]models.Subscription, error) {
+	var subs []models.Subscription
 
 	query := r.db.Model(&models.Subscription{}).
-		Select("COALESCE(SUM(price), 0) AS total, COUNT(*) AS count").
 		Where(
 			"start_date <= ? AND (end_date IS NULL OR end_date >= ?)",
 			endDate, startDate,
 		)
 
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+	err := withDetails(applyFilter(query, filter)).Order("created_at").Find(&subs).Error
+	if err != nil {
+		return nil, err
 	}
 
-	if serviceName != "" {
-		query = query.Where("service_name ILIKE ?", "%"+serviceName+"%")
+	return subs, nil
+}
+
+// ListTrialsEndingBy returns the subscriptions still in trial whose trial
+// ends in or before lastTrialMonth, soonest first.
+func (r *subscriptionRepository) ListTrialsEndingBy(lastTrialMonth models.YearMonth, userID string) ([]models.Subscription, error) {
+	var subs []models.Subscription
+
+	query := r.db.Model(&models.Subscription{}).
+		Where("status = ? AND trial_end <= ?", models.StatusTrial, lastTrialMonth)
+
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
 	}
 
-	err := query.Scan(&result).Error
+	err := withDetails(query).Order("trial_end, created_at").Find(&subs).Error
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	return subs, nil
+}
+
+// Transition stores a lifecycle change: the subscription with its pauses and
+// the history entry are written in one transaction. Like Update it reports
+// false when the subscription changed since it was read.
+func (r *subscriptionRepository) Transition(sub *models.Subscription, change *models.SubscriptionStatusChange) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ok, err := saveVersioned(tx, sub)
+		if err != nil || !ok {
+			return err
+		}
+		updated = true
+
+		for i := range sub.Pauses {
+			if err := tx.Save(&sub.Pauses[i]).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(change).Error
+	})
+
+	return updated, err
+}
+
+func (r *subscriptionRepository) Prices(id uuid.UUID) ([]models.SubscriptionPrice, error) {
+	var prices []models.SubscriptionPrice
+
+	err := r.db.
+		Where("subscription_id = ?", id).
+		Order("effective_from").
+		Find(&prices).Error
+
+	return prices, err
+}
+
+func (r *subscriptionRepository) History(id uuid.UUID) ([]models.SubscriptionStatusChange, error) {
+	var changes []models.SubscriptionStatusChange
+
+	err := r.db.
+		Where("subscription_id = ?", id).
+		Order("created_at").
+		Find(&changes).Error
+
+	return changes, err
+}
+
+// applyFilter adds the conditions of filter to query. Sorting is left to the
+// caller because the total query does not need it.
+func applyFilter(query *gorm.DB, filter dtos.SubscriptionFilter) *gorm.DB {
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ServiceName != "" {
+		if filter.NameMatch == dtos.NameMatchExact {
+			query = query.Where("lower(service_name) = lower(?)", filter.ServiceName)
+		} else {
+			query = query.Where("service_name ILIKE ?", "%"+filter.ServiceName+"%")
+		}
+	}
+	if filter.Category != "" {
+		query = query.Where("lower(category) = lower(?)", filter.Category)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PriceMin != nil {
+		query = query.Where(currentPrice+" >= ?", *filter.PriceMin)
+	}
+	if filter.PriceMax != nil {
+		query = query.Where(currentPrice+" <= ?", *filter.PriceMax)
+	}
+	if filter.ActiveAt != nil {
+		query = query.Where(
+			"start_date <= ? AND (end_date IS NULL OR end_date >= ?)",
+			*filter.ActiveAt, *filter.ActiveAt,
+		)
+	}
+	if filter.StartFrom != nil {
+		query = query.Where("start_date >= ?", *filter.StartFrom)
+	}
+	if filter.StartTo != nil {
+		query = query.Where("start_date <= ?", *filter.StartTo)
+	}
+	if filter.HasEndDate != nil {
+		if *filter.HasEndDate {
+			query = query.Where("end_date IS NOT NULL")
+		} else {
+			query = query.Where("end_date IS NULL")
+		}
+	}
+	return query
+}
+
+// withDetails preloads what cost calculations need besides the row itself.
+func withDetails(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Pauses").
+		Preload("Prices", func(db *gorm.DB) *gorm.DB {
+			return db.Order("effective_from")
+		})
 }
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// maxAnalyticsMonths bounds the length of an analytics series.
+const maxAnalyticsMonths = 120
+
+// MonthlySpending returns one entry per month of the inclusive [from, to]
+// window. The charges and counts are computed by the database; only the
+// conversion of each month's per-currency sums into currency happens here,
+// at the same rates the total cost uses.
+func (s *subscriptionService) MonthlySpending(
+	from, to models.YearMonth,
+	filter dtos.SubscriptionFilter,
+	currency models.Currency,
+) (*dtos.MonthlySpendingResponse, error) {
+	if err := checkAnalyticsPeriod(from, to); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repo.MonthlyActivity(from, to, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+	converter := newCurrencyConverter(s.rates, currency)
+
+	months := make([]dtos.MonthSpending, from.MonthsUntil(to)+1)
+	for i := range months {
+		months[i] = dtos.MonthSpending{Month: from.AddMonths(i).String()}
+	}
+	for _, row := range rows {
+		month := &months[from.MonthsUntil(row.Month)]
+		month.Active += row.Active
+		month.New += row.Started
+		month.Ended += row.Ended
+		if row.Amount == 0 {
+			continue
+		}
+
+		amount, err := converter.convert(row.Amount, row.Currency, row.Month)
+		if err != nil {
+			return nil, err
+		}
+		month.Total += amount
+		if month.ByCurrency == nil {
+			month.ByCurrency = make(map[string]int64)
+		}
+		month.ByCurrency[string(row.Currency)] += row.Amount
+	}
+
+	return &dtos.MonthlySpendingResponse{
+		Currency:  string(currency),
+		Months:    months,
+		RatesUsed: converter.ratesUsed(),
+	}, nil
+}
+
+func checkAnalyticsPeriod(from, to models.YearMonth) error {
+	if from.IsZero() || to.IsZero() {
+		return fmt.Errorf("%w: from and to are required", ErrInvalidPeriod)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("%w: to must not be before from", ErrInvalidPeriod)
+	}
+	if from.MonthsUntil(to) >= maxAnalyticsMonths {
+		return fmt.Errorf("%w: at most %d months can be reported at once", ErrInvalidPeriod, maxAnalyticsMonths)
+	}
+	return nil
+}
+
+const (
+	// otherGroupKey names the group that sums up the groups beyond the top
+	// ones.
+	otherGroupKey = "other"
+	// uncategorizedGroupKey names the group of subscriptions without a
+	// category.
+	uncategorizedGroupKey = "uncategorized"
+)
+
+// SpendingBreakdown splits the charges of the inclusive [from, to] window,
+// counted and converted as for the total cost, by service name, category,
+// user or month. Categories are grouped ignoring case, under their lower-case
+// form. Services, categories and users are ordered by cost, most expensive
+// first, and cut to the top ones when top is positive; months are listed in
+// calendar order, including those without charges, and are never cut.
+func (s *subscriptionService) SpendingBreakdown(
+	from, to models.YearMonth,
+	filter dtos.SubscriptionFilter,
+	currency models.Currency,
+	groupBy string,
+	top int,
+) (*dtos.SpendingBreakdownResponse, error) {
+	if err := checkAnalyticsPeriod(from, to); err != nil {
+		return nil, err
+	}
+
+	subs, err := s.repo.ListActiveInRange(from, to, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+	converter := newCurrencyConverter(s.rates, currency)
+
+	type group struct {
+		key  string
+		cost int64
+		subs map[uuid.UUID]bool
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+	groupOf := func(key string) *group {
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{key: key, subs: make(map[uuid.UUID]bool)}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		return g
+	}
+	if groupBy == dtos.GroupByMonth {
+		for month := from; !month.After(to); month = month.AddMonths(1) {
+			groupOf(month.String())
+		}
+	}
+
+	var total int64
+	for _, sub := range subs {
+		for _, ch := range chargesInRange(sub, from, to) {
+			amount, err := converter.convert(ch.Amount, sub.Currency, ch.Month)
+			if err != nil {
+				return nil, err
+			}
+
+			var key string
+			switch groupBy {
+			case dtos.GroupByServiceName:
+				key = sub.ServiceName
+			case dtos.GroupByCategory:
+				key = uncategorizedGroupKey
+				if sub.Category != nil {
+					key = strings.ToLower(*sub.Category)
+				}
+			case dtos.GroupByUserID:
+				key = sub.UserID.String()
+			default:
+				key = ch.Month.String()
+			}
+			g := groupOf(key)
+			g.cost += amount
+			g.subs[sub.ID] = true
+			total += amount
+		}
+	}
+
+	if groupBy != dtos.GroupByMonth {
+		sort.SliceStable(groups, func(i, j int) bool {
+			if groups[i].cost != groups[j].cost {
+				return groups[i].cost > groups[j].cost
+			}
+			return groups[i].key < groups[j].key
+		})
+	}
+
+	resp := &dtos.SpendingBreakdownResponse{
+		GroupBy:   groupBy,
+		Currency:  string(currency),
+		Total:     total,
+		Groups:    []dtos.SpendingGroup{},
+		RatesUsed: converter.ratesUsed(),
+	}
+	for i, g := range groups {
+		if top > 0 && i == top && groupBy != dtos.GroupByMonth {
+			other := dtos.SpendingGroup{Key: otherGroupKey, Other: true}
+			for _, rest := range groups[top:] {
+				other.Cost += rest.cost
+				other.Count += len(rest.subs)
+			}
+			resp.Groups = append(resp.Groups, other)
+			break
+		}
+		resp.Groups = append(resp.Groups, dtos.SpendingGroup{
+			Key:   g.key,
+			Cost:  g.cost,
+			Count: len(g.subs),
+		})
+	}
+	costs := make([]int64, len(resp.Groups))
+	for i, g := range resp.Groups {
+		costs[i] = g.Cost
+	}
+	for i, share := range shares(costs, total) {
+		resp.Groups[i].Share = share
+	}
+	return resp, nil
+}
+
+// shares returns each of costs as a percentage of total with two decimals.
+// Rounding uses the largest remainder method, so that the shares add up to
+// exactly 100 instead of 99.99 for three equal parts.
+func shares(costs []int64, total int64) []float64 {
+	result := make([]float64, len(costs))
+	if total == 0 {
+		return result
+	}
+
+	// Work in hundredths of a percent: every cost gets the floor of its
+	// exact share, and the hundredths left over go to the largest
+	// remainders.
+	const whole = 10000
+	hundredths := make([]int64, len(costs))
+	order := make([]int, len(costs))
+	left := int64(whole)
+	for i, cost := range costs {
+		hundredths[i] = cost * whole / total
+		left -= hundredths[i]
+		order[i] = i
+	}
+	remainder := func(i int) int64 { return costs[i] * whole % total }
+	sort.SliceStable(order, func(a, b int) bool { return remainder(order[a]) > remainder(order[b]) })
+	for _, i := range order[:left] {
+		hundredths[i]++
+	}
+
+	for i := range result {
+		result[i] = float64(hundredths[i]) / 100
+	}
+	return result
+}
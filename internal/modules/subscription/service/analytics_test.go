@@ -0,0 +1,153 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// sumOfShares adds up shares in hundredths, so float rounding cannot hide a
+// sum of 99.99.
+func sumOfShares(groups []dtos.SpendingGroup) int64 {
+	var sum int64
+	for _, g := range groups {
+		sum += int64(math.Round(g.Share * 100))
+	}
+	return sum
+}
+
+func monthly(serviceName string, category *string, price int64) models.Subscription {
+	return models.Subscription{
+		ID: uuid.New(), ServiceName: serviceName, Category: category, Price: price, Currency: "RUB",
+		BillingPeriod: models.BillingMonthly, UserID: bulkUser, StartDate: ym(time.July, 2025),
+	}
+}
+
+func TestShares(t *testing.T) {
+	tests := []struct {
+		name  string
+		costs []int64
+		want  []float64
+	}{
+		{name: "exact", costs: []int64{50, 25, 25}, want: []float64{50, 25, 25}},
+		{name: "thirds", costs: []int64{1, 1, 1}, want: []float64{33.34, 33.33, 33.33}},
+		{name: "largest remainder wins", costs: []int64{2, 1, 1, 1, 1, 1}, want: []float64{28.57, 14.29, 14.29, 14.29, 14.28, 14.28}},
+		{name: "tiny part", costs: []int64{999999, 1}, want: []float64{100, 0}},
+		{name: "single", costs: []int64{42}, want: []float64{100}},
+		{name: "nothing charged", costs: []int64{0, 0}, want: []float64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var total int64
+			for _, cost := range tt.costs {
+				total += cost
+			}
+			if got := shares(tt.costs, total); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shares(%v) = %v, want %v", tt.costs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpendingBreakdown(t *testing.T) {
+	subs := []models.Subscription{
+		monthly("Netflix", ptr("streaming"), 1000),
+		monthly("Okko", nil, 1000),
+		monthly("Spotify", ptr("Music"), 1000),
+		monthly("Zvuk", ptr("music"), 500),
+		monthly("Premier", ptr("Streaming"), 500),
+		monthly("Wink", ptr("tv"), 100),
+	}
+	from, to := ym(time.July, 2025), ym(time.September, 2025)
+
+	tests := []struct {
+		name       string
+		groupBy    string
+		top        int
+		wantKeys   []string
+		wantCosts  []int64
+		wantCounts []int
+	}{
+		{
+			name:       "by category",
+			groupBy:    dtos.GroupByCategory,
+			wantKeys:   []string{"music", "streaming", "uncategorized", "tv"},
+			wantCosts:  []int64{4500, 4500, 3000, 300},
+			wantCounts: []int{2, 2, 1, 1},
+		},
+		{
+			name:       "top categories",
+			groupBy:    dtos.GroupByCategory,
+			top:        2,
+			wantKeys:   []string{"music", "streaming", "other"},
+			wantCosts:  []int64{4500, 4500, 3300},
+			wantCounts: []int{2, 2, 2},
+		},
+		{
+			name:       "top services",
+			groupBy:    dtos.GroupByServiceName,
+			top:        3,
+			wantKeys:   []string{"Netflix", "Okko", "Spotify", "other"},
+			wantCosts:  []int64{3000, 3000, 3000, 3300},
+			wantCounts: []int{1, 1, 1, 3},
+		},
+		{
+			name:       "top beyond the groups",
+			groupBy:    dtos.GroupByCategory,
+			top:        10,
+			wantKeys:   []string{"music", "streaming", "uncategorized", "tv"},
+			wantCosts:  []int64{4500, 4500, 3000, 300},
+			wantCounts: []int{2, 2, 1, 1},
+		},
+		{
+			name:       "by month",
+			groupBy:    dtos.GroupByMonth,
+			wantKeys:   []string{"07-2025", "08-2025", "09-2025"},
+			wantCosts:  []int64{4100, 4100, 4100},
+			wantCounts: []int{6, 6, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewSubscriptionService(activeRepo{subs: subs}, nil, "RUB", 0, "off")
+
+			resp, err := svc.SpendingBreakdown(from, to, dtos.SubscriptionFilter{}, "", tt.groupBy, tt.top)
+			if err != nil {
+				t.Fatalf("SpendingBreakdown() error = %v", err)
+			}
+			if resp.Total != 12300 {
+				t.Errorf("total = %d, want 12300", resp.Total)
+			}
+
+			var keys []string
+			var costs []int64
+			var counts []int
+			for i, g := range resp.Groups {
+				keys = append(keys, g.Key)
+				costs = append(costs, g.Cost)
+				counts = append(counts, g.Count)
+				if wantOther := tt.top > 0 && i == tt.top; g.Other != wantOther {
+					t.Errorf("group %q other = %v, want %v", g.Key, g.Other, wantOther)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) || !reflect.DeepEqual(costs, tt.wantCosts) || !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("groups = %v %v %v, want %v %v %v", keys, costs, counts, tt.wantKeys, tt.wantCosts, tt.wantCounts)
+			}
+			if sum := sumOfShares(resp.Groups); sum != 10000 {
+				t.Errorf("shares %+v add up to %d hundredths, want 100%%", resp.Groups, sum)
+			}
+		})
+	}
+}
+
+func ptr(value string) *string {
+	return &value
+}
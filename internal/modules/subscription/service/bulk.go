@@ -0,0 +1,226 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
+)
+
+var (
+	ErrInvalidBulkOperation = apperr.New(apperr.Invalid, "invalid-bulk-operation", "invalid bulk operation")
+	// ErrNotApplied marks the operations of an atomic bulk request that were
+	// rolled back because another one failed.
+	ErrNotApplied = apperr.New(apperr.FailedDependency, "not-applied", "not applied because another operation failed")
+)
+
+// BulkOutcome is the result of one bulk operation. Subscription is set for
+// successful creates and updates. Warnings name the likely duplicates of a
+// create when the duplicate guard warns.
+type BulkOutcome struct {
+	Op           string
+	ID           *uuid.UUID
+	Subscription *models.Subscription
+	Warnings     []string
+	Err          error
+
+	// userID is the owner of the subscription a successful operation changed.
+	userID *uuid.UUID
+}
+
+// bulkOp is an operation whose payload has been decoded and validated.
+type bulkOp struct {
+	op      string
+	id      uuid.UUID
+	ifMatch []int64
+	create  *models.Subscription
+	update  dtos.UpdateSubscriptionRequest
+}
+
+// Bulk applies req.Operations and reports the outcome of each, in order,
+// and whether anything was committed. validate checks create payloads the
+// way the create endpoint does.
+func (s *subscriptionService) Bulk(req dtos.BulkRequest, validate func(any) error) ([]BulkOutcome, bool, error) {
+	// Observers hear about each user once, after everything is committed,
+	// rather than from inside an atomic request's transaction.
+	quiet := *s
+	quiet.observer = nil
+	outcomes, committed, err := quiet.bulk(req, validate)
+	if err != nil || !committed {
+		return outcomes, committed, err
+	}
+
+	var userIDs []uuid.UUID
+	for _, outcome := range outcomes {
+		if outcome.Err == nil && outcome.userID != nil {
+			userIDs = append(userIDs, *outcome.userID)
+		}
+	}
+	s.spendChanged(userIDs...)
+	return outcomes, committed, nil
+}
+
+func (s *subscriptionService) bulk(req dtos.BulkRequest, validate func(any) error) ([]BulkOutcome, bool, error) {
+	atomic := req.Mode == "" || req.Mode == dtos.BulkAtomic
+	if !atomic && req.Mode != dtos.BulkBestEffort {
+		return nil, false, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidBulkOperation, dtos.BulkAtomic, dtos.BulkBestEffort)
+	}
+
+	outcomes := make([]BulkOutcome, len(req.Operations))
+	ops := make([]*bulkOp, len(req.Operations))
+	for i, raw := range req.Operations {
+		outcomes[i] = BulkOutcome{Op: raw.Op, ID: raw.ID}
+		ops[i], outcomes[i].Err = s.prepareBulkOp(raw, validate)
+	}
+
+	if atomic {
+		return s.bulkAtomic(ops, outcomes)
+	}
+	committed := false
+	created := make(map[uuid.UUID]int)
+	for i, op := range ops {
+		if op == nil {
+			continue
+		}
+		s.applyBulkOp(op, i, created, &outcomes[i])
+		committed = committed || outcomes[i].Err == nil
+	}
+	return outcomes, committed, nil
+}
+
+// bulkAtomic applies ops in request order in one transaction. Nothing is
+// applied if any operation fails, including in preparation.
+func (s *subscriptionService) bulkAtomic(ops []*bulkOp, outcomes []BulkOutcome) ([]BulkOutcome, bool, error) {
+	if slices.Contains(ops, nil) {
+		return notApplied(outcomes), false, nil
+	}
+
+	failed := errors.New("bulk operation failed")
+	err := s.repo.WithTx(func(repo repository.SubscriptionRepository) error {
+		tx := *s
+		tx.repo = repo
+
+		created := make(map[uuid.UUID]int)
+		for i, op := range ops {
+			tx.applyBulkOp(op, i, created, &outcomes[i])
+			if outcomes[i].Err != nil {
+				return failed
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, failed) {
+		return notApplied(outcomes), false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return outcomes, true, nil
+}
+
+// applyBulkOp runs op, the operation at index, and records the result in
+// outcome. Creates pass the duplicate guard first, which also checks them
+// against the subscriptions created by earlier operations, recorded in
+// created.
+func (s *subscriptionService) applyBulkOp(op *bulkOp, index int, created map[uuid.UUID]int, outcome *BulkOutcome) {
+	switch op.op {
+	case dtos.BulkCreate:
+		var existing []models.Subscription
+		var operations []int
+		existing, operations, outcome.Err = s.guardDuplicates(op.create, created)
+		if outcome.Err != nil {
+			return
+		}
+		if outcome.Err = s.repo.Create(op.create); outcome.Err != nil {
+			return
+		}
+		created[op.create.ID] = index
+		outcome.ID = &op.create.ID
+		outcome.Subscription = op.create
+		outcome.Warnings = duplicateWarnings(existing, operations)
+	case dtos.BulkUpdate:
+		outcome.Subscription, outcome.Err = s.Update(op.id, op.update, op.ifMatch)
+	case dtos.BulkDelete:
+		var deleted *models.Subscription
+		if deleted, outcome.Err = s.delete(op.id, op.ifMatch); outcome.Err == nil {
+			outcome.userID = &deleted.UserID
+		}
+	}
+	if outcome.Subscription != nil && outcome.Err == nil {
+		outcome.userID = &outcome.Subscription.UserID
+	}
+}
+
+func (s *subscriptionService) prepareBulkOp(raw dtos.BulkOperation, validate func(any) error) (*bulkOp, error) {
+	op := &bulkOp{op: raw.Op}
+	if raw.Version != nil {
+		op.ifMatch = []int64{*raw.Version}
+	}
+
+	switch raw.Op {
+	case dtos.BulkCreate:
+		var req dtos.CreateSubscriptionRequest
+		if err := decodeBulkData(raw.Data, &req); err != nil {
+			return nil, err
+		}
+		if err := validate(req); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBulkOperation, err)
+		}
+		sub, err := s.newSubscription(req)
+		if err != nil {
+			return nil, err
+		}
+		op.create = sub
+	case dtos.BulkUpdate, dtos.BulkDelete:
+		if raw.ID == nil {
+			return nil, fmt.Errorf("%w: id is required for %s", ErrInvalidBulkOperation, raw.Op)
+		}
+		op.id = *raw.ID
+		if raw.Op == dtos.BulkUpdate {
+			if err := decodeBulkData(raw.Data, &op.update); err != nil {
+				return nil, err
+			}
+			if err := validate(op.update); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidBulkOperation, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: op must be create, update or delete", ErrInvalidBulkOperation)
+	}
+	return op, nil
+}
+
+func decodeBulkData(data json.RawMessage, dst any) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: data is required", ErrInvalidBulkOperation)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBulkOperation, err)
+	}
+	return nil
+}
+
+// notApplied marks every operation that did not fail itself as rolled back.
+func notApplied(outcomes []BulkOutcome) []BulkOutcome {
+	for i := range outcomes {
+		if outcomes[i].Err == nil {
+			outcomes[i].Err = ErrNotApplied
+		}
+		outcomes[i].Subscription = nil
+		outcomes[i].Warnings = nil
+		if outcomes[i].Op == dtos.BulkCreate {
+			outcomes[i].ID = nil
+		}
+	}
+	return outcomes
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
+)
+
+// memorySubs keeps subscriptions in memory, in insertion order, and logs
+// every write. Deleted subscriptions stay with DeletedAt set, like the soft
+// delete of the real table. WithTx restores the state on error.
+type memorySubs struct {
+	repository.SubscriptionRepository
+	subs   []models.Subscription
+	writes []string
+}
+
+func (r *memorySubs) WithTx(fn func(repo repository.SubscriptionRepository) error) error {
+	subs, writes := slices.Clone(r.subs), slices.Clone(r.writes)
+	if err := fn(r); err != nil {
+		r.subs, r.writes = subs, writes
+		return err
+	}
+	return nil
+}
+
+func (r *memorySubs) find(id uuid.UUID) int {
+	return slices.IndexFunc(r.subs, func(sub models.Subscription) bool { return sub.ID == id })
+}
+
+func (r *memorySubs) Create(sub *models.Subscription) error {
+	sub.ID = uuid.New()
+	r.subs = append(r.subs, *sub)
+	r.writes = append(r.writes, "create "+sub.ServiceName)
+	return nil
+}
+
+func (r *memorySubs) GetByID(id uuid.UUID) (*models.Subscription, error) {
+	i := r.find(id)
+	if i < 0 || r.subs[i].DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	sub := r.subs[i]
+	return &sub, nil
+}
+
+func (r *memorySubs) Update(sub *models.Subscription, newPrice *models.SubscriptionPrice) (bool, error) {
+	i := r.find(sub.ID)
+	if i < 0 || r.subs[i].DeletedAt.Valid || r.subs[i].Version != sub.Version {
+		return false, nil
+	}
+	sub.Version++
+	r.subs[i] = *sub
+	r.writes = append(r.writes, "update "+sub.ServiceName)
+	return true, nil
+}
+
+func (r *memorySubs) Delete(id uuid.UUID, version int64) (bool, error) {
+	i := r.find(id)
+	if i < 0 || r.subs[i].DeletedAt.Valid || r.subs[i].Version != version {
+		return false, nil
+	}
+	r.subs[i].DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+	r.writes = append(r.writes, "delete "+r.subs[i].ServiceName)
+	return true, nil
+}
+
+func (r *memorySubs) StreamByUser(userID string, month models.YearMonth, fn func(sub models.Subscription) error) error {
+	for _, sub := range r.subs {
+		if sub.DeletedAt.Valid || (userID != "" && sub.UserID.String() != userID) {
+			continue
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var bulkUser = uuid.MustParse("60601fee-2bf1-4721-ae6f-7636e79a0cba")
+
+// storedSub is an active subscription of bulkUser from July 2025.
+func storedSub(serviceName string) models.Subscription {
+	return models.Subscription{
+		ID: uuid.New(), ServiceName: serviceName, Price: 39900, Currency: "RUB",
+		BillingPeriod: models.BillingMonthly, Status: models.StatusActive, UserID: bulkUser,
+		StartDate: models.NewYearMonth(2025, time.July), Version: 1,
+	}
+}
+
+func createOp(serviceName string) dtos.BulkOperation {
+	data := fmt.Sprintf(`{"service_name": %q, "price": 39900, "user_id": %q, "start_date": "07-2025"}`, serviceName, bulkUser)
+	return dtos.BulkOperation{Op: dtos.BulkCreate, Data: json.RawMessage(data)}
+}
+
+func updateOp(id uuid.UUID, serviceName string) dtos.BulkOperation {
+	return dtos.BulkOperation{Op: dtos.BulkUpdate, ID: &id, Data: json.RawMessage(fmt.Sprintf(`{"service_name": %q}`, serviceName))}
+}
+
+func deleteOp(id uuid.UUID) dtos.BulkOperation {
+	return dtos.BulkOperation{Op: dtos.BulkDelete, ID: &id}
+}
+
+func valid(any) error { return nil }
+
+func bulkErrors(outcomes []BulkOutcome) []error {
+	errs := make([]error, len(outcomes))
+	for i, outcome := range outcomes {
+		errs[i] = outcome.Err
+	}
+	return errs
+}
+
+func TestBulkAppliesInRequestOrder(t *testing.T) {
+	for _, mode := range []string{dtos.BulkAtomic, dtos.BulkBestEffort} {
+		t.Run(mode, func(t *testing.T) {
+			netflix := storedSub("Netflix")
+			repo := &memorySubs{subs: []models.Subscription{netflix}}
+			svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardReject)
+
+			// Creating Netflix again only passes the duplicate guard because
+			// the delete before it has been applied.
+			outcomes, committed, err := svc.Bulk(dtos.BulkRequest{Mode: mode, Operations: []dtos.BulkOperation{
+				updateOp(netflix.ID, "Netflix Basic"),
+				deleteOp(netflix.ID),
+				createOp("Netflix"),
+				createOp("Kion"),
+			}}, valid)
+			if err != nil {
+				t.Fatalf("Bulk() error = %v", err)
+			}
+			if !committed {
+				t.Fatalf("Bulk() not committed: %v", bulkErrors(outcomes))
+			}
+			for i, outcome := range outcomes {
+				if outcome.Err != nil {
+					t.Errorf("operation %d failed: %v", i, outcome.Err)
+				}
+			}
+			want := []string{"update Netflix Basic", "delete Netflix Basic", "create Netflix", "create Kion"}
+			if !reflect.DeepEqual(repo.writes, want) {
+				t.Errorf("writes = %q, want %q", repo.writes, want)
+			}
+		})
+	}
+}
+
+func TestBulkAtomicRollsBack(t *testing.T) {
+	netflix := storedSub("Netflix")
+	missing := uuid.New()
+
+	tests := []struct {
+		name       string
+		guard      string
+		operations []dtos.BulkOperation
+		wantErrs   []error
+	}{
+		{
+			name:       "failed update",
+			operations: []dtos.BulkOperation{createOp("Kion"), deleteOp(netflix.ID), updateOp(missing, "Okko")},
+			wantErrs:   []error{ErrNotApplied, ErrNotApplied, ErrSubscriptionNotFound},
+		},
+		{
+			name:       "outdated version",
+			operations: []dtos.BulkOperation{createOp("Kion"), {Op: dtos.BulkDelete, ID: &netflix.ID, Version: new(int64)}},
+			wantErrs:   []error{ErrNotApplied, ErrPreconditionFailed},
+		},
+		{
+			name:       "duplicate of an earlier create",
+			guard:      dtos.DuplicateGuardReject,
+			operations: []dtos.BulkOperation{createOp("Kion"), createOp("kion")},
+			wantErrs:   []error{ErrNotApplied, ErrDuplicateSubscription},
+		},
+		{
+			name:       "invalid operation",
+			operations: []dtos.BulkOperation{createOp("Kion"), {Op: dtos.BulkUpdate, Data: json.RawMessage(`{}`)}},
+			wantErrs:   []error{ErrNotApplied, ErrInvalidBulkOperation},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memorySubs{subs: []models.Subscription{netflix}}
+			svc := NewSubscriptionService(repo, nil, "RUB", 0, tt.guard)
+
+			outcomes, committed, err := svc.Bulk(dtos.BulkRequest{Operations: tt.operations}, valid)
+			if err != nil {
+				t.Fatalf("Bulk() error = %v", err)
+			}
+			if committed {
+				t.Error("Bulk() committed, want a rollback")
+			}
+			for i, outcome := range outcomes {
+				if !errors.Is(outcome.Err, tt.wantErrs[i]) {
+					t.Errorf("operation %d error = %v, want %v", i, outcome.Err, tt.wantErrs[i])
+				}
+				if outcome.Subscription != nil || (outcome.Op == dtos.BulkCreate && outcome.ID != nil) {
+					t.Errorf("operation %d reports %+v from a rolled back request", i, outcome)
+				}
+			}
+			if len(repo.writes) != 0 || !reflect.DeepEqual(repo.subs, []models.Subscription{netflix}) {
+				t.Errorf("stored %+v after writes %q, want the request rolled back", repo.subs, repo.writes)
+			}
+		})
+	}
+}
+
+func TestBulkBestEffort(t *testing.T) {
+	netflix := storedSub("Netflix")
+	missing := uuid.New()
+	repo := &memorySubs{subs: []models.Subscription{netflix}}
+	svc := NewSubscriptionService(repo, nil, "RUB", 0, "off")
+
+	outcomes, committed, err := svc.Bulk(dtos.BulkRequest{Mode: dtos.BulkBestEffort, Operations: []dtos.BulkOperation{
+		createOp("Kion"),
+		updateOp(missing, "Okko"),
+		{Op: "rename"},
+		deleteOp(netflix.ID),
+	}}, valid)
+	if err != nil {
+		t.Fatalf("Bulk() error = %v", err)
+	}
+	if !committed {
+		t.Error("Bulk() not committed, want the successful operations kept")
+	}
+
+	wantErrs := []error{nil, ErrSubscriptionNotFound, ErrInvalidBulkOperation, nil}
+	for i, outcome := range outcomes {
+		if !errors.Is(outcome.Err, wantErrs[i]) {
+			t.Errorf("operation %d error = %v, want %v", i, outcome.Err, wantErrs[i])
+		}
+	}
+	if created := outcomes[0]; created.ID == nil || created.Subscription == nil || created.Subscription.ServiceName != "Kion" {
+		t.Errorf("create outcome = %+v, want the stored subscription", created)
+	}
+	want := []string{"create Kion", "delete Netflix"}
+	if !reflect.DeepEqual(repo.writes, want) {
+		t.Errorf("writes = %q, want %q", repo.writes, want)
+	}
+}
+
+func TestBulkDuplicateWarnings(t *testing.T) {
+	repo := &memorySubs{subs: []models.Subscription{storedSub("Netflix")}}
+	svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardWarn)
+
+	outcomes, committed, err := svc.Bulk(dtos.BulkRequest{Operations: []dtos.BulkOperation{
+		createOp("Kion"),
+		createOp("KION"),
+		createOp("netflix"),
+	}}, valid)
+	if err != nil || !committed {
+		t.Fatalf("Bulk() = committed %v, error %v", committed, err)
+	}
+	if len(outcomes[0].Warnings) != 0 {
+		t.Errorf("first create warned %q", outcomes[0].Warnings)
+	}
+	want := []string{"overlaps the subscription created by operation 0"}
+	if !reflect.DeepEqual(outcomes[1].Warnings, want) {
+		t.Errorf("second create warned %q, want %q", outcomes[1].Warnings, want)
+	}
+	if len(outcomes[2].Warnings) != 1 {
+		t.Errorf("third create warned %q, want the stored Netflix", outcomes[2].Warnings)
+	}
+}
+
+func TestBulkKeepsFieldErrors(t *testing.T) {
+	repo := &memorySubs{}
+	svc := NewSubscriptionService(repo, nil, "RUB", 0, "off")
+	invalid := func(any) error {
+		return apperr.Validation("validation failed", apperr.FieldError{Field: "price", Message: "price must be 0 or greater"})
+	}
+
+	outcomes, _, err := svc.Bulk(dtos.BulkRequest{Operations: []dtos.BulkOperation{createOp("Kion")}}, invalid)
+	if err != nil {
+		t.Fatalf("Bulk() error = %v", err)
+	}
+	if !errors.Is(outcomes[0].Err, ErrInvalidBulkOperation) {
+		t.Errorf("error = %v, want %v", outcomes[0].Err, ErrInvalidBulkOperation)
+	}
+	want := []apperr.FieldError{{Field: "price", Message: "price must be 0 or greater"}}
+	if got := apperr.FieldsOf(outcomes[0].Err, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("field errors = %+v, want %+v", got, want)
+	}
+}
+
+func TestBulkRejectsUnknownModeFirst(t *testing.T) {
+	repo := &memorySubs{subs: []models.Subscription{storedSub("Netflix")}}
+	svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardReject)
+	validated := false
+	validate := func(any) error {
+		validated = true
+		return nil
+	}
+
+	_, _, err := svc.Bulk(dtos.BulkRequest{Mode: "eventually", Operations: []dtos.BulkOperation{createOp("Netflix")}}, validate)
+	if !errors.Is(err, ErrInvalidBulkOperation) {
+		t.Errorf("Bulk() error = %v, want %v", err, ErrInvalidBulkOperation)
+	}
+	if validated || len(repo.writes) != 0 {
+		t.Errorf("validated %v and wrote %q before rejecting the mode", validated, repo.writes)
+	}
+}
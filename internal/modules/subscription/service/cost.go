@@ -0,0 +1,219 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	ratesService "github.com/Ilmyrat1822/subs/internal/modules/exchangerate/service"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// charge is a single payment of a subscription's price.
+type charge struct {
+	Date   time.Time
+	Month  models.YearMonth
+	Amount int64
+}
+
+// chargesInRange lists the charges of sub that fall inside the inclusive
+// [from, to] window. Charges start on the first day of the first month after
+// the trial, or of the start month without one, and repeat every billing
+// period until the end of the end month. Charges due while the subscription
+// is paused are skipped without shifting the schedule, and each charge uses
+// the price in force in its month.
+func chargesInRange(sub models.Subscription, from, to models.YearMonth) []charge {
+	last := to
+	if sub.EndDate != nil && sub.EndDate.Before(last) {
+		last = *sub.EndDate
+	}
+	first := sub.BillingStart()
+	if last.Before(from) || last.Before(first) {
+		return nil
+	}
+
+	var charges []charge
+
+	if sub.BillingPeriod.IsWeekly() {
+		windowStart, lastDay := from.Time(), last.LastDay()
+		date := first.Time()
+		if date.Before(windowStart) {
+			days := int(windowStart.Sub(date).Hours() / 24)
+			date = date.AddDate(0, 0, (days+6)/7*7)
+		}
+		for ; !date.After(lastDay); date = date.AddDate(0, 0, 7) {
+			month := models.YearMonthOf(date)
+			if sub.IsPausedIn(month) {
+				continue
+			}
+			charges = append(charges, charge{Date: date, Month: month, Amount: sub.PriceIn(month)})
+		}
+		return charges
+	}
+
+	interval := sub.BillingPeriod.IntervalMonths()
+	month := first
+	if month.Before(from) {
+		steps := (month.MonthsUntil(from) + interval - 1) / interval
+		month = month.AddMonths(steps * interval)
+	}
+	for ; !month.After(last); month = month.AddMonths(interval) {
+		if sub.IsPausedIn(month) {
+			continue
+		}
+		charges = append(charges, charge{Date: month.Time(), Month: month, Amount: sub.PriceIn(month)})
+	}
+	return charges
+}
+
+// ExchangeRates looks up the rate for converting charges of one month. It is
+// implemented by the exchange rate module.
+type ExchangeRates interface {
+	LookupRate(from, to models.Currency, month models.YearMonth) (*models.ExchangeRate, float64, error)
+}
+
+type rateKey struct {
+	from  models.Currency
+	month models.YearMonth
+}
+
+// currencyConverter converts charges into a single currency, looking every
+// rate up once and remembering which ones were applied.
+type currencyConverter struct {
+	rates  ExchangeRates
+	target models.Currency
+	cache  map[rateKey]float64
+	used   []dtos.RateUsed
+}
+
+func newCurrencyConverter(rates ExchangeRates, target models.Currency) *currencyConverter {
+	return &currencyConverter{
+		rates:  rates,
+		target: target,
+		cache:  make(map[rateKey]float64),
+	}
+}
+
+func (c *currencyConverter) convert(amount int64, from models.Currency, month models.YearMonth) (int64, error) {
+	if from == c.target {
+		return amount, nil
+	}
+
+	key := rateKey{from: from, month: month}
+	multiplier, ok := c.cache[key]
+	if !ok {
+		stored, value, err := c.rates.LookupRate(from, c.target, month)
+		if err != nil {
+			if errors.Is(err, ratesService.ErrRateUnavailable) {
+				return 0, fmt.Errorf("%w: %v", ErrExchangeRateUnavailable, err)
+			}
+			return 0, err
+		}
+		multiplier = value
+		c.cache[key] = value
+		c.used = append(c.used, dtos.RateUsed{
+			Month:    month.String(),
+			From:     string(from),
+			To:       string(c.target),
+			Rate:     value,
+			RateDate: stored.RateDate.Format(time.DateOnly),
+		})
+	}
+
+	return models.ConvertMinor(amount, from, c.target, multiplier), nil
+}
+
+// ratesUsed returns the applied rates ordered by month and currency.
+func (c *currencyConverter) ratesUsed() []dtos.RateUsed {
+	sort.SliceStable(c.used, func(i, j int) bool {
+		a, _ := models.ParseYearMonth(c.used[i].Month)
+		b, _ := models.ParseYearMonth(c.used[j].Month)
+		if a != b {
+			return a.Before(b)
+		}
+		return c.used[i].From < c.used[j].From
+	})
+	return c.used
+}
+
+// calculateTotalCost adds up every charge that falls inside the window,
+// converted into the converter's currency. When withBreakdown is set the
+// per-month and per-subscription amounts are returned alongside the total.
+func calculateTotalCost(
+	subs []models.Subscription,
+	from, to models.YearMonth,
+	converter *currencyConverter,
+	withBreakdown bool,
+) (*dtos.TotalCostResponse, error) {
+	resp := &dtos.TotalCostResponse{
+		Currency:   string(converter.target),
+		ByCurrency: make(map[string]int64),
+	}
+
+	perMonth := make([]int64, from.MonthsUntil(to)+1)
+	var bySubscription []dtos.SubscriptionCost
+
+	for _, sub := range subs {
+		charges := chargesInRange(sub, from, to)
+		if len(charges) == 0 {
+			continue
+		}
+
+		cost := dtos.SubscriptionCost{
+			SubscriptionID: sub.ID,
+			ServiceName:    sub.ServiceName,
+			Price:          sub.Price,
+			Currency:       string(sub.Currency),
+			BillingPeriod:  string(sub.BillingPeriod),
+			Charges:        len(charges),
+		}
+		for _, ch := range charges {
+			resp.ByCurrency[string(sub.Currency)] += ch.Amount
+
+			amount, err := converter.convert(ch.Amount, sub.Currency, ch.Month)
+			if err != nil {
+				return nil, err
+			}
+			cost.Total += amount
+			perMonth[from.MonthsUntil(ch.Month)] += amount
+
+			if n := len(cost.ByMonth); n > 0 && cost.ByMonth[n-1].Month == ch.Month.String() {
+				cost.ByMonth[n-1].Amount += amount
+				continue
+			}
+			cost.Months++
+			cost.ByMonth = append(cost.ByMonth, dtos.MonthCost{
+				Month:  ch.Month.String(),
+				Amount: amount,
+			})
+		}
+
+		resp.Total += cost.Total
+		resp.Count++
+		bySubscription = append(bySubscription, cost)
+	}
+
+	resp.RatesUsed = converter.ratesUsed()
+
+	if withBreakdown {
+		byMonth := make([]dtos.MonthCost, len(perMonth))
+		for i, amount := range perMonth {
+			byMonth[i] = dtos.MonthCost{
+				Month:  from.AddMonths(i).String(),
+				Amount: amount,
+			}
+		}
+		if bySubscription == nil {
+			bySubscription = []dtos.SubscriptionCost{}
+		}
+		resp.Breakdown = &dtos.TotalCostBreakdown{
+			ByMonth:        byMonth,
+			BySubscription: bySubscription,
+		}
+	}
+
+	return resp, nil
+}
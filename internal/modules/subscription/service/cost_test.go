@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	ratesService "github.com/Ilmyrat1822/subs/internal/modules/exchangerate/service"
+)
+
+// chargeList formats charges as "date amount" for comparison.
+func chargeList(charges []charge) []string {
+	var list []string
+	for _, ch := range charges {
+		list = append(list, fmt.Sprintf("%s %d", ch.Date.Format(time.DateOnly), ch.Amount))
+	}
+	return list
+}
+
+func TestChargesInRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		sub      models.Subscription
+		from, to models.YearMonth
+		want     []string
+	}{
+		{
+			name: "monthly inside the window",
+			sub:  models.Subscription{Price: 39900, StartDate: ym(time.July, 2025)},
+			from: ym(time.July, 2025), to: ym(time.September, 2025),
+			want: []string{"2025-07-01 39900", "2025-08-01 39900", "2025-09-01 39900"},
+		},
+		{
+			name: "monthly started before the window",
+			sub:  models.Subscription{Price: 100, StartDate: ym(time.January, 2025)},
+			from: ym(time.March, 2025), to: ym(time.April, 2025),
+			want: []string{"2025-03-01 100", "2025-04-01 100"},
+		},
+		{
+			name: "end month is charged",
+			sub:  models.Subscription{Price: 100, StartDate: ym(time.July, 2025), EndDate: ymPtr(time.August, 2025)},
+			from: ym(time.January, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-07-01 100", "2025-08-01 100"},
+		},
+		{
+			name: "ended before the window",
+			sub:  models.Subscription{Price: 100, StartDate: ym(time.January, 2025), EndDate: ymPtr(time.February, 2025)},
+			from: ym(time.March, 2025), to: ym(time.December, 2025),
+			want: nil,
+		},
+		{
+			name: "starts after the window",
+			sub:  models.Subscription{Price: 100, StartDate: ym(time.January, 2026)},
+			from: ym(time.March, 2025), to: ym(time.December, 2025),
+			want: nil,
+		},
+		{
+			name: "quarterly keeps its schedule across the window start",
+			sub:  models.Subscription{Price: 30000, BillingPeriod: models.BillingQuarterly, StartDate: ym(time.January, 2025)},
+			from: ym(time.February, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-04-01 30000", "2025-07-01 30000", "2025-10-01 30000"},
+		},
+		{
+			name: "yearly",
+			sub:  models.Subscription{Price: 120000, BillingPeriod: models.BillingYearly, StartDate: ym(time.March, 2024)},
+			from: ym(time.January, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-03-01 120000"},
+		},
+		{
+			name: "every 2 months",
+			sub:  models.Subscription{Price: 500, BillingPeriod: models.EveryNMonths(2), StartDate: ym(time.June, 2025)},
+			from: ym(time.July, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-08-01 500", "2025-10-01 500", "2025-12-01 500"},
+		},
+		{
+			name: "weekly from the start day",
+			sub:  models.Subscription{Price: 1200, BillingPeriod: models.BillingWeekly, StartDate: ym(time.July, 2025)},
+			from: ym(time.July, 2025), to: ym(time.July, 2025),
+			want: []string{"2025-07-01 1200", "2025-07-08 1200", "2025-07-15 1200", "2025-07-22 1200", "2025-07-29 1200"},
+		},
+		{
+			name: "weekly keeps its weekday across the window start",
+			sub:  models.Subscription{Price: 1200, BillingPeriod: models.BillingWeekly, StartDate: ym(time.July, 2025)},
+			from: ym(time.August, 2025), to: ym(time.August, 2025),
+			want: []string{"2025-08-05 1200", "2025-08-12 1200", "2025-08-19 1200", "2025-08-26 1200"},
+		},
+		{
+			name: "trial months are free",
+			sub:  models.Subscription{Price: 100, StartDate: ym(time.July, 2025), TrialEnd: ymPtr(time.August, 2025)},
+			from: ym(time.July, 2025), to: ym(time.October, 2025),
+			want: []string{"2025-09-01 100", "2025-10-01 100"},
+		},
+		{
+			name: "trial moves the schedule of longer periods",
+			sub: models.Subscription{
+				Price: 30000, BillingPeriod: models.BillingQuarterly,
+				StartDate: ym(time.January, 2025), TrialEnd: ymPtr(time.January, 2025),
+			},
+			from: ym(time.January, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-02-01 30000", "2025-05-01 30000", "2025-08-01 30000", "2025-11-01 30000"},
+		},
+		{
+			name: "paused months are skipped",
+			sub: models.Subscription{
+				Price: 100, StartDate: ym(time.July, 2025),
+				Pauses: []models.SubscriptionPause{{PausedFrom: ym(time.August, 2025), ResumedFrom: ymPtr(time.October, 2025)}},
+			},
+			from: ym(time.July, 2025), to: ym(time.October, 2025),
+			want: []string{"2025-07-01 100", "2025-10-01 100"},
+		},
+		{
+			name: "an open pause skips the rest",
+			sub: models.Subscription{
+				Price: 100, StartDate: ym(time.July, 2025),
+				Pauses: []models.SubscriptionPause{{PausedFrom: ym(time.September, 2025)}},
+			},
+			from: ym(time.July, 2025), to: ym(time.December, 2025),
+			want: []string{"2025-07-01 100", "2025-08-01 100"},
+		},
+		{
+			name: "a pause does not shift a quarterly schedule",
+			sub: models.Subscription{
+				Price: 30000, BillingPeriod: models.BillingQuarterly, StartDate: ym(time.January, 2025),
+				Pauses: []models.SubscriptionPause{{PausedFrom: ym(time.April, 2025), ResumedFrom: ymPtr(time.May, 2025)}},
+			},
+			from: ym(time.January, 2025), to: ym(time.September, 2025),
+			want: []string{"2025-01-01 30000", "2025-07-01 30000"},
+		},
+		{
+			name: "each charge uses the price in force",
+			sub: models.Subscription{
+				Price: 200, StartDate: ym(time.July, 2025),
+				Prices: []models.SubscriptionPrice{
+					{Price: 100, EffectiveFrom: ym(time.July, 2025)},
+					{Price: 200, EffectiveFrom: ym(time.September, 2025)},
+				},
+			},
+			from: ym(time.July, 2025), to: ym(time.October, 2025),
+			want: []string{"2025-07-01 100", "2025-08-01 100", "2025-09-01 200", "2025-10-01 200"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chargeList(chargesInRange(tt.sub, tt.from, tt.to))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chargesInRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fixedRates converts at a fixed rate per source currency.
+type fixedRates map[models.Currency]float64
+
+func (r fixedRates) LookupRate(from, to models.Currency, month models.YearMonth) (*models.ExchangeRate, float64, error) {
+	rate, ok := r[from]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s to %s for %s", ratesService.ErrRateUnavailable, from, to, month)
+	}
+	stored := &models.ExchangeRate{RateDate: month.Time(), BaseCurrency: from, QuoteCurrency: to, Rate: rate}
+	return stored, rate, nil
+}
+
+func TestCalculateTotalCost(t *testing.T) {
+	rub := models.Subscription{
+		ID: uuid.New(), ServiceName: "Yandex Plus", Price: 39900, Currency: "RUB",
+		BillingPeriod: models.BillingMonthly, StartDate: ym(time.July, 2025),
+	}
+	usd := models.Subscription{
+		ID: uuid.New(), ServiceName: "Netflix", Price: 1000, Currency: "USD",
+		BillingPeriod: models.BillingMonthly, StartDate: ym(time.August, 2025),
+	}
+	later := models.Subscription{
+		ID: uuid.New(), ServiceName: "Later", Price: 100, Currency: "RUB",
+		BillingPeriod: models.BillingMonthly, StartDate: ym(time.January, 2026),
+	}
+
+	tests := []struct {
+		name       string
+		subs       []models.Subscription
+		rates      fixedRates
+		wantTotal  int64
+		wantCount  int64
+		wantByCurr map[string]int64
+		wantMonths []int64
+		wantRates  int
+		wantErr    error
+	}{
+		{
+			name:       "single currency",
+			subs:       []models.Subscription{rub, later},
+			wantTotal:  79800,
+			wantCount:  1,
+			wantByCurr: map[string]int64{"RUB": 79800},
+			wantMonths: []int64{39900, 39900},
+		},
+		{
+			name:       "converted once per month",
+			subs:       []models.Subscription{rub, usd},
+			rates:      fixedRates{"USD": 90},
+			wantTotal:  79800 + 90000,
+			wantCount:  2,
+			wantByCurr: map[string]int64{"RUB": 79800, "USD": 1000},
+			wantMonths: []int64{39900, 129900},
+			wantRates:  1,
+		},
+		{
+			name:    "missing rate",
+			subs:    []models.Subscription{rub, usd},
+			rates:   fixedRates{},
+			wantErr: ErrExchangeRateUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := newCurrencyConverter(tt.rates, "RUB")
+			resp, err := calculateTotalCost(tt.subs, ym(time.July, 2025), ym(time.August, 2025), converter, true)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("calculateTotalCost() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("calculateTotalCost() error = %v", err)
+			}
+
+			if resp.Total != tt.wantTotal || resp.Count != tt.wantCount {
+				t.Errorf("total = %d over %d subscriptions, want %d over %d", resp.Total, resp.Count, tt.wantTotal, tt.wantCount)
+			}
+			if !reflect.DeepEqual(resp.ByCurrency, tt.wantByCurr) {
+				t.Errorf("by currency = %v, want %v", resp.ByCurrency, tt.wantByCurr)
+			}
+			if len(resp.RatesUsed) != tt.wantRates {
+				t.Errorf("rates used = %v, want %d", resp.RatesUsed, tt.wantRates)
+			}
+
+			var months []int64
+			for _, month := range resp.Breakdown.ByMonth {
+				months = append(months, month.Amount)
+			}
+			if !reflect.DeepEqual(months, tt.wantMonths) {
+				t.Errorf("by month = %v, want %v", months, tt.wantMonths)
+			}
+			var sum int64
+			for _, cost := range resp.Breakdown.BySubscription {
+				sum += cost.Total
+			}
+			if sum != resp.Total {
+				t.Errorf("subscription totals add up to %d, want %d", sum, resp.Total)
+			}
+		})
+	}
+}
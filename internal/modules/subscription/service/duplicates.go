@@ -0,0 +1,179 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+var ErrDuplicateSubscription = apperr.New(apperr.Conflict, "duplicate-subscription", "an overlapping subscription to the same service exists")
+
+// DuplicateError names the subscriptions that a new one would duplicate:
+// existing ones, and those created by earlier operations of the same bulk
+// request, by index.
+type DuplicateError struct {
+	Existing   []models.Subscription
+	Operations []int
+}
+
+func (e *DuplicateError) Error() string {
+	refs := make([]string, 0, len(e.Existing)+len(e.Operations))
+	for _, sub := range e.Existing {
+		refs = append(refs, sub.ID.String())
+	}
+	for _, index := range e.Operations {
+		refs = append(refs, fmt.Sprintf("operation %d", index))
+	}
+	return fmt.Sprintf("%s: %s", ErrDuplicateSubscription.Message, strings.Join(refs, ", "))
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return ErrDuplicateSubscription
+}
+
+// FieldErrors points at each conflicting subscription for the problem
+// response.
+func (e *DuplicateError) FieldErrors() []apperr.FieldError {
+	messages := duplicateWarnings(e.Existing, e.Operations)
+	fields := make([]apperr.FieldError, len(messages))
+	for i, message := range messages {
+		fields[i] = apperr.FieldError{Field: "service_name", Message: message}
+	}
+	return fields
+}
+
+// DescribeDuplicate says which existing subscription a new one overlaps.
+func DescribeDuplicate(sub models.Subscription) string {
+	period := "from " + sub.StartDate.String()
+	if sub.EndDate != nil {
+		period += " to " + sub.EndDate.String()
+	}
+	return fmt.Sprintf("overlaps subscription %s (%s, %s)", sub.ID, sub.ServiceName, period)
+}
+
+// duplicateWarnings describes the likely duplicates of a new subscription.
+func duplicateWarnings(existing []models.Subscription, operations []int) []string {
+	var warnings []string
+	for _, sub := range existing {
+		warnings = append(warnings, DescribeDuplicate(sub))
+	}
+	for _, index := range operations {
+		warnings = append(warnings, fmt.Sprintf("overlaps the subscription created by operation %d", index))
+	}
+	return warnings
+}
+
+// Duplicates finds the subscriptions of userID, or of every user when it is
+// empty, that likely duplicate each other: their service names are equal
+// once normalized and their periods overlap. Subscriptions are read one user
+// at a time, so memory use is bounded by the largest user.
+func (s *subscriptionService) Duplicates(userID string) (*dtos.DuplicatesResponse, error) {
+	resp := &dtos.DuplicatesResponse{Groups: []dtos.DuplicateGroup{}}
+
+	var userSubs []models.Subscription
+	flush := func() {
+		resp.Groups = append(resp.Groups, duplicateGroups(userSubs)...)
+		userSubs = userSubs[:0]
+	}
+	err := s.repo.StreamByUser(userID, models.CurrentMonth(), func(sub models.Subscription) error {
+		if len(userSubs) > 0 && userSubs[0].UserID != sub.UserID {
+			flush()
+		}
+		userSubs = append(userSubs, sub)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+
+	resp.Count = len(resp.Groups)
+	return resp, nil
+}
+
+// duplicateGroups groups the subscriptions of one user, ordered by start
+// month, by normalized service name and returns the runs of overlapping
+// periods with more than one subscription.
+func duplicateGroups(subs []models.Subscription) []dtos.DuplicateGroup {
+	byKey := make(map[string][]models.Subscription)
+	var keys []string
+	for _, sub := range subs {
+		key := models.NormalizeServiceName(sub.ServiceName)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], sub)
+	}
+
+	var groups []dtos.DuplicateGroup
+	for _, key := range keys {
+		var run []models.Subscription
+		// runEnd is the latest end month of run, nil while one is open-ended.
+		var runEnd *models.YearMonth
+		emit := func() {
+			if len(run) > 1 {
+				groups = append(groups, dtos.DuplicateGroup{
+					UserID:        run[0].UserID,
+					ServiceKey:    key,
+					Subscriptions: run,
+				})
+			}
+		}
+		for _, sub := range byKey[key] {
+			if len(run) > 0 && runEnd != nil && runEnd.Before(sub.StartDate) {
+				emit()
+				run = nil
+			}
+			if len(run) == 0 || (runEnd != nil && (sub.EndDate == nil || sub.EndDate.After(*runEnd))) {
+				runEnd = sub.EndDate
+			}
+			run = append(run, sub)
+		}
+		emit()
+	}
+	return groups
+}
+
+// guardDuplicates applies the duplicate guard to sub, which is about to be
+// created. created maps the subscriptions stored by earlier operations of the
+// same bulk request to the index of their operation. In reject mode likely
+// duplicates fail with a *DuplicateError; in warn mode they are returned, as
+// existing subscriptions and indexes of earlier creates.
+func (s *subscriptionService) guardDuplicates(sub *models.Subscription, created map[uuid.UUID]int) ([]models.Subscription, []int, error) {
+	if s.duplicateGuard != dtos.DuplicateGuardWarn && s.duplicateGuard != dtos.DuplicateGuardReject {
+		return nil, nil, nil
+	}
+
+	key := models.NormalizeServiceName(sub.ServiceName)
+	var existing []models.Subscription
+	var operations []int
+	err := s.repo.StreamByUser(sub.UserID.String(), models.CurrentMonth(), func(other models.Subscription) error {
+		if models.NormalizeServiceName(other.ServiceName) != key || !other.Overlaps(sub.StartDate, sub.EndDate) {
+			return nil
+		}
+		if index, ok := created[other.ID]; ok {
+			operations = append(operations, index)
+		} else {
+			existing = append(existing, other)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(operations)
+
+	if len(existing) == 0 && len(operations) == 0 {
+		return nil, nil, nil
+	}
+	if s.duplicateGuard == dtos.DuplicateGuardReject {
+		return nil, nil, &DuplicateError{Existing: existing, Operations: operations}
+	}
+	return existing, operations, nil
+}
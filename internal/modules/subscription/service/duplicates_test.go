@@ -0,0 +1,115 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+func ym(month time.Month, year int) models.YearMonth {
+	return models.NewYearMonth(year, month)
+}
+
+func ymPtr(month time.Month, year int) *models.YearMonth {
+	m := ym(month, year)
+	return &m
+}
+
+// period returns a subscription to name from start to end, open-ended when
+// end is zero.
+func period(name string, start, end time.Month) models.Subscription {
+	sub := models.Subscription{ServiceName: name, StartDate: ym(start, 2025)}
+	if end != 0 {
+		sub.EndDate = ymPtr(end, 2025)
+	}
+	return sub
+}
+
+func TestDuplicateGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		subs []models.Subscription
+		want []string
+	}{
+		{
+			name: "single subscription",
+			subs: []models.Subscription{period("Netflix", time.January, 0)},
+			want: nil,
+		},
+		{
+			name: "different services",
+			subs: []models.Subscription{period("Netflix", time.January, 0), period("Kion", time.January, 0)},
+			want: nil,
+		},
+		{
+			name: "spellings of one service overlap",
+			subs: []models.Subscription{period("Yandex Plus", time.January, time.June), period("yandex-plus", time.March, 0)},
+			want: []string{"yandexplus: Yandex Plus 01-2025, yandex-plus 03-2025"},
+		},
+		{
+			name: "back to back periods",
+			subs: []models.Subscription{period("Netflix", time.January, time.March), period("Netflix", time.April, 0)},
+			want: nil,
+		},
+		{
+			name: "sharing the end month",
+			subs: []models.Subscription{period("Netflix", time.January, time.March), period("Netflix", time.March, 0)},
+			want: []string{"netflix: Netflix 01-2025, Netflix 03-2025"},
+		},
+		{
+			name: "three open-ended",
+			subs: []models.Subscription{
+				period("Netflix", time.January, 0), period("Netflix", time.February, 0), period("Netflix", time.March, 0),
+			},
+			want: []string{"netflix: Netflix 01-2025, Netflix 02-2025, Netflix 03-2025"},
+		},
+		{
+			name: "a long period joins later ones",
+			subs: []models.Subscription{
+				period("Netflix", time.January, time.December), period("Netflix", time.February, time.March), period("Netflix", time.June, time.July),
+			},
+			want: []string{"netflix: Netflix 01-2025, Netflix 02-2025, Netflix 06-2025"},
+		},
+		{
+			name: "separate runs",
+			subs: []models.Subscription{
+				period("Netflix", time.January, time.February), period("Netflix", time.February, time.March),
+				period("Netflix", time.June, time.July), period("Netflix", time.July, 0),
+			},
+			want: []string{
+				"netflix: Netflix 01-2025, Netflix 02-2025",
+				"netflix: Netflix 06-2025, Netflix 07-2025",
+			},
+		},
+		{
+			name: "groups per service",
+			subs: []models.Subscription{
+				period("Kion", time.January, 0), period("Netflix", time.January, 0),
+				period("Netflix", time.February, 0), period("KION", time.March, 0),
+			},
+			want: []string{
+				"kion: Kion 01-2025, KION 03-2025",
+				"netflix: Netflix 01-2025, Netflix 02-2025",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, group := range duplicateGroups(tt.subs) {
+				members := make([]string, len(group.Subscriptions))
+				for i, sub := range group.Subscriptions {
+					members[i] = sub.ServiceName + " " + sub.StartDate.String()
+				}
+				got = append(got, group.ServiceKey+": "+strings.Join(members, ", "))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("duplicateGroups() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
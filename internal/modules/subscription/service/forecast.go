@@ -0,0 +1,101 @@
+package service
+
+import (
+	"math"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+const (
+	defaultForecastMonths = 12
+
+	// forecastConfidence is the probability covered by the forecast band,
+	// and forecastZ the matching quantile of the normal distribution.
+	forecastConfidence = 0.9
+	forecastZ          = 1.645
+)
+
+// Forecast projects the spending of the subscriptions matching filter over
+// the next months, starting with the current one. Charges are counted and
+// converted as for the total cost, so subscriptions drop out after their
+// end date and scheduled prices apply from their month; future months use
+// the latest known exchange rates.
+//
+// With churn assumptions, a subscription still running is taken to be
+// cancelled each month with the probability for its service, so it is still
+// charged i months from now with probability (1-p)^i. The band around the
+// expected cost is a normal approximation of the sum of these independent
+// charges, capped by the committed cost.
+func (s *subscriptionService) Forecast(
+	months int,
+	filter dtos.SubscriptionFilter,
+	currency models.Currency,
+	churn *dtos.ChurnAssumptions,
+) (*dtos.ForecastResponse, error) {
+	if months <= 0 {
+		months = defaultForecastMonths
+	}
+	from := models.CurrentMonth()
+	to := from.AddMonths(months - 1)
+
+	subs, err := s.repo.ListActiveInRange(from, to, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+	converter := newCurrencyConverter(s.rates, currency)
+
+	committed := make([]int64, months)
+	expected := make([]float64, months)
+	variance := make([]float64, months)
+	for _, sub := range subs {
+		perMonth := make([]int64, months)
+		for _, ch := range chargesInRange(sub, from, to) {
+			amount, err := converter.convert(ch.Amount, sub.Currency, ch.Month)
+			if err != nil {
+				return nil, err
+			}
+			perMonth[from.MonthsUntil(ch.Month)] += amount
+		}
+
+		var p float64
+		if churn != nil {
+			p = churn.For(sub.ServiceName)
+		}
+		for i, amount := range perMonth {
+			survival := math.Pow(1-p, float64(i))
+			committed[i] += amount
+			expected[i] += float64(amount) * survival
+			variance[i] += float64(amount) * float64(amount) * survival * (1 - survival)
+		}
+	}
+
+	resp := &dtos.ForecastResponse{
+		Currency: string(currency),
+		Months:   make([]dtos.ForecastMonth, months),
+	}
+	for i := range resp.Months {
+		month := dtos.ForecastMonth{
+			Month:     from.AddMonths(i).String(),
+			Expected:  int64(math.Round(expected[i])),
+			Committed: committed[i],
+		}
+		if churn != nil {
+			margin := forecastZ * math.Sqrt(variance[i])
+			low := max(0, int64(math.Round(expected[i]-margin)))
+			high := min(committed[i], int64(math.Round(expected[i]+margin)))
+			month.Low, month.High = &low, &high
+		}
+		resp.Months[i] = month
+		resp.Total += month.Expected
+	}
+	if churn != nil {
+		resp.Confidence = forecastConfidence
+	}
+	resp.RatesUsed = converter.ratesUsed()
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
+)
+
+// activeRepo serves a fixed list of subscriptions as the active ones; the
+// other repository methods are not used by the forecast.
+type activeRepo struct {
+	repository.SubscriptionRepository
+	subs []models.Subscription
+}
+
+func (r activeRepo) ListActiveInRange(_, _ models.YearMonth, _ dtos.SubscriptionFilter) ([]models.Subscription, error) {
+	return r.subs, nil
+}
+
+func TestForecast(t *testing.T) {
+	now := models.CurrentMonth()
+	nextMonth := now.AddMonths(1)
+	subs := []models.Subscription{
+		{ServiceName: "Netflix", Price: 1000, Currency: "RUB", BillingPeriod: models.BillingMonthly, StartDate: now},
+		{ServiceName: "Kion", Price: 500, Currency: "RUB", BillingPeriod: models.BillingMonthly, StartDate: now, EndDate: &nextMonth},
+	}
+	bound := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name           string
+		months         int
+		churn          *dtos.ChurnAssumptions
+		wantExpected   []int64
+		wantCommitted  []int64
+		wantLow        []*int64
+		wantHigh       []*int64
+		wantTotal      int64
+		wantConfidence float64
+	}{
+		{
+			name:          "without churn",
+			months:        3,
+			wantExpected:  []int64{1500, 1500, 1000},
+			wantCommitted: []int64{1500, 1500, 1000},
+			wantLow:       []*int64{nil, nil, nil},
+			wantHigh:      []*int64{nil, nil, nil},
+			wantTotal:     4000,
+		},
+		{
+			// Netflix survives i months with probability 0.5^i; its
+			// standard deviation is 1000*sqrt(s(1-s)), so 500 and 433 in
+			// the later months, widened by 1.645.
+			name:           "with churn",
+			months:         3,
+			churn:          &dtos.ChurnAssumptions{ByService: map[string]float64{"netflix": 0.5}},
+			wantExpected:   []int64{1500, 1000, 250},
+			wantCommitted:  []int64{1500, 1500, 1000},
+			wantLow:        []*int64{bound(1500), bound(178), bound(0)},
+			wantHigh:       []*int64{bound(1500), bound(1500), bound(962)},
+			wantTotal:      2750,
+			wantConfidence: forecastConfidence,
+		},
+		{
+			name:           "certain churn",
+			months:         2,
+			churn:          &dtos.ChurnAssumptions{Default: 1},
+			wantExpected:   []int64{1500, 0},
+			wantCommitted:  []int64{1500, 1500},
+			wantLow:        []*int64{bound(1500), bound(0)},
+			wantHigh:       []*int64{bound(1500), bound(0)},
+			wantTotal:      1500,
+			wantConfidence: forecastConfidence,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &subscriptionService{repo: activeRepo{subs: subs}, defaultCurrency: "RUB"}
+			resp, err := svc.Forecast(tt.months, dtos.SubscriptionFilter{}, "", tt.churn)
+			if err != nil {
+				t.Fatalf("Forecast() error = %v", err)
+			}
+
+			var expected, committed []int64
+			var low, high []*int64
+			for i, month := range resp.Months {
+				if want := now.AddMonths(i).String(); month.Month != want {
+					t.Errorf("month %d = %s, want %s", i, month.Month, want)
+				}
+				expected = append(expected, month.Expected)
+				committed = append(committed, month.Committed)
+				low = append(low, month.Low)
+				high = append(high, month.High)
+			}
+			if !reflect.DeepEqual(expected, tt.wantExpected) {
+				t.Errorf("expected = %v, want %v", expected, tt.wantExpected)
+			}
+			if !reflect.DeepEqual(committed, tt.wantCommitted) {
+				t.Errorf("committed = %v, want %v", committed, tt.wantCommitted)
+			}
+			if !reflect.DeepEqual(low, tt.wantLow) || !reflect.DeepEqual(high, tt.wantHigh) {
+				t.Errorf("band = %v..%v, want %v..%v", deref(low), deref(high), deref(tt.wantLow), deref(tt.wantHigh))
+			}
+			if resp.Total != tt.wantTotal || resp.Confidence != tt.wantConfidence {
+				t.Errorf("total = %d at %v, want %d at %v", resp.Total, resp.Confidence, tt.wantTotal, tt.wantConfidence)
+			}
+		})
+	}
+}
+
+func TestForecastDefaultMonths(t *testing.T) {
+	svc := &subscriptionService{repo: activeRepo{}, defaultCurrency: "RUB"}
+	resp, err := svc.Forecast(0, dtos.SubscriptionFilter{}, "", nil)
+	if err != nil {
+		t.Fatalf("Forecast() error = %v", err)
+	}
+	if len(resp.Months) != defaultForecastMonths || resp.Currency != "RUB" {
+		t.Errorf("Forecast(0) = %d months in %s, want %d in RUB", len(resp.Months), resp.Currency, defaultForecastMonths)
+	}
+}
+
+func deref(values []*int64) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		if v != nil {
+			out[i] = *v
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+var ErrInvalidTransition = apperr.New(apperr.Conflict, "invalid-transition", "invalid status transition")
+
+func (s *subscriptionService) Pause(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error) {
+	return s.transition(id, models.ActionPause, req)
+}
+
+func (s *subscriptionService) Resume(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error) {
+	return s.transition(id, models.ActionResume, req)
+}
+
+func (s *subscriptionService) Cancel(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error) {
+	return s.transition(id, models.ActionCancel, req)
+}
+
+func (s *subscriptionService) Reactivate(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error) {
+	return s.transition(id, models.ActionReactivate, req)
+}
+
+func (s *subscriptionService) History(id uuid.UUID) ([]models.SubscriptionStatusChange, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	return s.repo.History(id)
+}
+
+// transition validates action against the state machine, applies its side
+// effects on pauses and end date, and records it in the history.
+func (s *subscriptionService) transition(id uuid.UUID, action models.SubscriptionAction, req dtos.StatusChangeRequest) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+
+	next, ok := sub.Status.Transition(action)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot %s a subscription that is %s", ErrInvalidTransition, action, sub.Status)
+	}
+
+	effective := models.CurrentMonth()
+	if req.Effective != nil {
+		effective = *req.Effective
+	}
+	if effective.Before(sub.StartDate) {
+		return nil, ErrInvalidPeriod.WithField("effective", "must not be before start_date")
+	}
+
+	change := &models.SubscriptionStatusChange{
+		SubscriptionID: sub.ID,
+		Action:         action,
+		FromStatus:     sub.Status,
+		ToStatus:       next,
+		EffectiveMonth: effective,
+		Reason:         req.Reason,
+	}
+
+	switch action {
+	case models.ActionPause:
+		sub.Pauses = append(sub.Pauses, models.SubscriptionPause{
+			SubscriptionID: sub.ID,
+			PausedFrom:     effective,
+		})
+	case models.ActionResume:
+		if err := resumePause(sub, effective); err != nil {
+			return nil, err
+		}
+	case models.ActionCancel:
+		change.PreviousEndDate = sub.EndDate
+		end := currentPeriodEnd(*sub, effective)
+		if sub.EndDate == nil || end.Before(*sub.EndDate) {
+			sub.EndDate = &end
+		}
+	case models.ActionConvert:
+		now := time.Now().UTC()
+		sub.TrialConvertedAt = &now
+	case models.ActionReactivate:
+		previous, err := s.endDateBeforeCancel(sub.ID)
+		if err != nil {
+			return nil, err
+		}
+		sub.EndDate = previous
+		if sub.OpenPause() != nil {
+			if err := resumePause(sub, effective); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sub.Status = next
+
+	updated, err := s.repo.Transition(sub, change)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrConcurrentUpdate
+	}
+	s.spendChanged(sub.UserID)
+	return sub, nil
+}
+
+func resumePause(sub *models.Subscription, effective models.YearMonth) error {
+	pause := sub.OpenPause()
+	if pause == nil {
+		return nil
+	}
+	if effective.Before(pause.PausedFrom) {
+		return fmt.Errorf("%w: cannot resume before the pause started in %s", ErrInvalidPeriod, pause.PausedFrom)
+	}
+	pause.ResumedFrom = &effective
+	return nil
+}
+
+// endDateBeforeCancel returns the end date the latest cancellation replaced.
+func (s *subscriptionService) endDateBeforeCancel(id uuid.UUID) (*models.YearMonth, error) {
+	history, err := s.repo.History(id)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Action == models.ActionCancel {
+			return history[i].PreviousEndDate, nil
+		}
+	}
+	return nil, nil
+}
+
+// currentPeriodEnd returns the last month covered by the charge made on or
+// before month, which is when a cancelled subscription stops running.
+// Weekly subscriptions, and trials cancelled before the first charge, run
+// until the end of month.
+func currentPeriodEnd(sub models.Subscription, month models.YearMonth) models.YearMonth {
+	first := sub.BillingStart()
+	if sub.BillingPeriod.IsWeekly() || month.Before(first) {
+		return month
+	}
+	interval := sub.BillingPeriod.IntervalMonths()
+	elapsed := first.MonthsUntil(month)
+	lastCharge := first.AddMonths(elapsed / interval * interval)
+	return lastCharge.AddMonths(interval - 1)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
+)
+
+var ErrInvalidCursor = apperr.New(apperr.Invalid, "invalid-cursor", "invalid cursor")
+
+// listPage reads one page of the listing, by cursor when page has one and by
+// offset otherwise. Both modes return next and prev cursors, so offset
+// clients can switch to cursors from any page. The total is counted in
+// offset mode unless IncludeTotal is false, and in cursor mode only when it
+// is true.
+func (s *subscriptionService) listPage(filter dtos.SubscriptionFilter, page dtos.PageRequest) ([]models.Subscription, *pagination.Meta, error) {
+	limit, offset := pagination.Normalize(page.Limit, page.Offset)
+	meta := &pagination.Meta{Limit: limit}
+
+	// One extra row tells whether there is anything beyond this page.
+	var subs []models.Subscription
+	var err error
+	var cursor *repository.Cursor
+	if page.Cursor != "" {
+		if cursor, err = repository.DecodeCursor(page.Cursor, filter.Sort); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		subs, err = s.repo.ListAfter(filter, limit+1, *cursor)
+	} else {
+		meta.Offset = &offset
+		subs, err = s.repo.List(filter, limit+1, offset)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	more := len(subs) > limit
+	if more {
+		if cursor != nil && cursor.Backward {
+			subs = subs[1:]
+		} else {
+			subs = subs[:limit]
+		}
+	}
+
+	if len(subs) > 0 {
+		var hasPrev, hasNext bool
+		switch {
+		case cursor == nil:
+			hasPrev, hasNext = offset > 0, more
+		case cursor.Backward:
+			hasPrev, hasNext = more, true
+		default:
+			hasPrev, hasNext = true, more
+		}
+		if hasPrev {
+			meta.Prev = repository.CursorAt(subs[0], filter.Sort, true).Encode()
+		}
+		if hasNext {
+			meta.Next = repository.CursorAt(subs[len(subs)-1], filter.Sort, false).Encode()
+		}
+	}
+
+	includeTotal := cursor == nil
+	if page.IncludeTotal != nil {
+		includeTotal = *page.IncludeTotal
+	}
+	if includeTotal {
+		total, err := s.repo.Count(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		n := int(total)
+		meta.Total = &n
+	}
+
+	return subs, meta, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// ErrInvalidPatch means a patch could not be applied or produced an invalid
+// subscription.
+var ErrInvalidPatch = apperr.New(apperr.Unprocessable, "invalid-patch", "invalid patch")
+
+// PatchFunc applies a patch to the JSON form of a dtos.SubscriptionDocument.
+// Typed errors it returns are passed on as they are; any other error makes
+// the patch invalid.
+type PatchFunc func(doc []byte) ([]byte, error)
+
+// Patch applies a patch to the editable fields of a subscription. Unlike
+// Update it can clear end_date and trial_end. The patched document is
+// validated as a whole with validate and the usual period rules.
+func (s *subscriptionService) Patch(id uuid.UUID, apply PatchFunc, ifMatch []int64, validate func(any) error) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	if err := checkVersion(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	current := dtos.SubscriptionDocument{
+		ServiceName:   sub.ServiceName,
+		Category:      sub.Category,
+		Price:         sub.PriceIn(models.CurrentMonth()),
+		Currency:      sub.Currency,
+		BillingPeriod: sub.BillingPeriod,
+		StartDate:     sub.StartDate,
+		EndDate:       sub.EndDate,
+		TrialEnd:      sub.TrialEnd,
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := apply(raw)
+	if err != nil {
+		if _, ok := apperr.As(err); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	var doc dtos.SubscriptionDocument
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if err := validate(doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	if !sameMonth(doc.TrialEnd, sub.TrialEnd) && sub.Status != models.StatusTrial {
+		return nil, ErrInvalidPatch.WithField("trial_end", "can only be changed during the trial")
+	}
+	if err := validatePeriod(doc.StartDate, doc.EndDate); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	if err := validateTrial(doc.StartDate, doc.EndDate, doc.TrialEnd); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	sub.ServiceName = doc.ServiceName
+	sub.Category = optionalCategory(doc.Category)
+	sub.Currency = doc.Currency
+	sub.BillingPeriod = doc.BillingPeriod
+	sub.StartDate = doc.StartDate
+	sub.EndDate = doc.EndDate
+	sub.TrialEnd = doc.TrialEnd
+
+	var newPrice *models.SubscriptionPrice
+	if doc.Price != current.Price {
+		if newPrice, err = schedulePrice(sub, doc.Price, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.repo.Update(sub, newPrice)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, lostRace(ifMatch)
+	}
+	s.spendChanged(sub.UserID)
+	return sub, nil
+}
+
+func sameMonth(a, b *models.YearMonth) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Compare(*b) == 0
+}
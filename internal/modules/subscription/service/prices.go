@@ -0,0 +1,112 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+func (s *subscriptionService) SchedulePrice(id uuid.UUID, req dtos.SchedulePriceRequest) ([]dtos.PriceTimelineEntry, error) {
+	sub, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	newPrice, err := schedulePrice(sub, req.Price, req.EffectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(sub, newPrice)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrConcurrentUpdate
+	}
+
+	s.spendChanged(sub.UserID)
+	return priceTimeline(*sub), nil
+}
+
+func (s *subscriptionService) Prices(id uuid.UUID) ([]dtos.PriceTimelineEntry, error) {
+	sub, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return priceTimeline(*sub), nil
+}
+
+// schedulePrice records a change to price from effective, the current month
+// by default, in sub's price history and returns the entry to store. Price
+// itself only changes when the new price is already in force.
+func schedulePrice(sub *models.Subscription, price int64, effective *models.YearMonth) (*models.SubscriptionPrice, error) {
+	if price < 0 {
+		return nil, ErrInvalidPeriod.WithField("price", "must not be negative")
+	}
+
+	month := models.CurrentMonth()
+	if effective != nil {
+		month = *effective
+	}
+	if month.Before(sub.StartDate) {
+		return nil, ErrInvalidPeriod.WithField("effective_from", "must not be before start_date")
+	}
+
+	entry := models.SubscriptionPrice{
+		SubscriptionID: sub.ID,
+		Price:          price,
+		EffectiveFrom:  month,
+	}
+
+	replaced := false
+	for i := range sub.Prices {
+		if sub.Prices[i].EffectiveFrom == month {
+			sub.Prices[i].Price = price
+			replaced = true
+		}
+	}
+	if !replaced {
+		sub.Prices = append(sub.Prices, entry)
+		sort.Slice(sub.Prices, func(i, j int) bool {
+			return sub.Prices[i].EffectiveFrom.Before(sub.Prices[j].EffectiveFrom)
+		})
+	}
+	sub.Price = sub.PriceIn(models.CurrentMonth())
+
+	return &entry, nil
+}
+
+// priceTimeline turns the price history, ordered by effective month, into
+// consecutive ranges.
+func priceTimeline(sub models.Subscription) []dtos.PriceTimelineEntry {
+	now := models.CurrentMonth()
+	timeline := make([]dtos.PriceTimelineEntry, len(sub.Prices))
+
+	for i, p := range sub.Prices {
+		entry := dtos.PriceTimelineEntry{
+			Price:         p.Price,
+			Currency:      string(sub.Currency),
+			EffectiveFrom: p.EffectiveFrom.String(),
+			Scheduled:     p.EffectiveFrom.After(now),
+		}
+
+		last := sub.EndDate
+		if i+1 < len(sub.Prices) {
+			prev := sub.Prices[i+1].EffectiveFrom.AddMonths(-1)
+			last = &prev
+		}
+		if last != nil {
+			effectiveTo := last.String()
+			entry.EffectiveTo = &effectiveTo
+		}
+		entry.Current = !entry.Scheduled && (last == nil || !last.Before(now))
+
+		timeline[i] = entry
+	}
+
+	return timeline
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// SpendObserver is told after a change that may have raised what a user is
+// going to spend. It is implemented by the budget module.
+type SpendObserver interface {
+	SpendChanged(userID uuid.UUID)
+}
+
+// ObserveSpend makes every change to subscriptions report to observer. It is
+// set after construction because the observer itself asks this service for
+// spend.
+func (s *subscriptionService) ObserveSpend(observer SpendObserver) {
+	s.observer = observer
+}
+
+// spendChanged tells the observer, if any, about each distinct user.
+func (s *subscriptionService) spendChanged(userIDs ...uuid.UUID) {
+	if s.observer == nil {
+		return
+	}
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if !seen[userID] {
+			seen[userID] = true
+			s.observer.SpendChanged(userID)
+		}
+	}
+}
+
+// Spend is what the subscriptions of userID are charged from one month to
+// another, converted to currency like the total. A serviceName restricts it
+// to subscriptions with that name and a category to those in it, both
+// ignoring case.
+func (s *subscriptionService) Spend(userID uuid.UUID, serviceName, category string, from, to models.YearMonth, currency models.Currency) (int64, error) {
+	filter := dtos.SubscriptionFilter{
+		UserID:      userID.String(),
+		ServiceName: serviceName,
+		NameMatch:   dtos.NameMatchExact,
+		Category:    category,
+	}
+	total, err := s.GetTotalCost(from, to, filter, currency, false)
+	if err != nil {
+		return 0, err
+	}
+	return total.Total, nil
+}
@@ -3,49 +3,162 @@ package service
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
 	"github.com/Ilmyrat1822/subs/internal/models"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
 	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
-	"gorm.io/gorm"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
 )
 
 type SubscriptionService interface {
-	Create(req dtos.CreateSubscriptionRequest) (*models.Subscription, error)
-	Get(id int) (*models.Subscription, error)
-	List(
-		userID, serviceName string,
-		limit, offset int,
-	) ([]models.Subscription, *dtos.PaginationMeta, error)
-	Update(id int, req dtos.UpdateSubscriptionRequest) (*models.Subscription, error)
-	Delete(id int) error
+	Create(req dtos.CreateSubscriptionRequest) (*models.Subscription, []models.Subscription, error)
+	Get(id uuid.UUID) (*models.Subscription, error)
+	List(filter dtos.SubscriptionFilter, page dtos.PageRequest) ([]dtos.SubscriptionListItem, *pagination.Meta, error)
+	Export(filter dtos.SubscriptionFilter, fn func(item dtos.SubscriptionListItem) error) error
+	Update(id uuid.UUID, req dtos.UpdateSubscriptionRequest, ifMatch []int64) (*models.Subscription, error)
+	Patch(id uuid.UUID, apply PatchFunc, ifMatch []int64, validate func(any) error) (*models.Subscription, error)
+	Delete(id uuid.UUID, ifMatch []int64) error
+	Trash(userID string, limit, offset int) ([]dtos.TrashItem, *pagination.Meta, error)
+	Restore(id uuid.UUID, ifMatch []int64) (*models.Subscription, []models.Subscription, error)
+	PurgeTrash() (int64, error)
+	Bulk(req dtos.BulkRequest, validate func(any) error) ([]BulkOutcome, bool, error)
+	Pause(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error)
+	Resume(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error)
+	Cancel(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error)
+	Reactivate(id uuid.UUID, req dtos.StatusChangeRequest) (*models.Subscription, error)
+	History(id uuid.UUID) ([]models.SubscriptionStatusChange, error)
+	SchedulePrice(id uuid.UUID, req dtos.SchedulePriceRequest) ([]dtos.PriceTimelineEntry, error)
+	Prices(id uuid.UUID) ([]dtos.PriceTimelineEntry, error)
+	TrialsEnding(within time.Duration, userID string) ([]dtos.TrialEndingItem, error)
+	Duplicates(userID string) (*dtos.DuplicatesResponse, error)
+	ConvertEndedTrials() (int, error)
 	GetTotalCost(
-		startDate, endDate, userID, serviceName string,
+		startDate, endDate models.YearMonth,
+		filter dtos.SubscriptionFilter,
+		currency models.Currency,
+		breakdown bool,
 	) (*dtos.TotalCostResponse, error)
+	MonthlySpending(
+		from, to models.YearMonth,
+		filter dtos.SubscriptionFilter,
+		currency models.Currency,
+	) (*dtos.MonthlySpendingResponse, error)
+	SpendingBreakdown(
+		from, to models.YearMonth,
+		filter dtos.SubscriptionFilter,
+		currency models.Currency,
+		groupBy string,
+		top int,
+	) (*dtos.SpendingBreakdownResponse, error)
+	Forecast(
+		months int,
+		filter dtos.SubscriptionFilter,
+		currency models.Currency,
+		churn *dtos.ChurnAssumptions,
+	) (*dtos.ForecastResponse, error)
+	Spend(userID uuid.UUID, serviceName, category string, from, to models.YearMonth, currency models.Currency) (int64, error)
+	ObserveSpend(observer SpendObserver)
 }
 
 type subscriptionService struct {
-	repo repository.SubscriptionRepository
+	repo            repository.SubscriptionRepository
+	rates           ExchangeRates
+	defaultCurrency models.Currency
+	trashRetention  time.Duration
+	duplicateGuard  string
+	observer        SpendObserver
 }
 
 func NewSubscriptionService(
 	repo repository.SubscriptionRepository,
+	rates ExchangeRates,
+	defaultCurrency string,
+	trashRetention time.Duration,
+	duplicateGuard string,
 ) SubscriptionService {
-	return &subscriptionService{repo: repo}
+	return &subscriptionService{
+		repo:            repo,
+		rates:           rates,
+		defaultCurrency: models.Currency(defaultCurrency),
+		trashRetention:  trashRetention,
+		duplicateGuard:  duplicateGuard,
+	}
+}
+
+// Create stores a new subscription. With the duplicate guard in warn mode it
+// also returns the existing subscriptions the new one likely duplicates.
+func (s *subscriptionService) Create(req dtos.CreateSubscriptionRequest) (*models.Subscription, []models.Subscription, error) {
+	sub, err := s.newSubscription(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	duplicates, _, err := s.guardDuplicates(sub, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.Create(sub); err != nil {
+		return nil, nil, err
+	}
+	s.spendChanged(sub.UserID)
+	return sub, duplicates, nil
 }
 
-func (s *subscriptionService) Create(req dtos.CreateSubscriptionRequest) (*models.Subscription, error) {
+// newSubscription validates req and builds the subscription it describes,
+// without storing it.
+func (s *subscriptionService) newSubscription(req dtos.CreateSubscriptionRequest) (*models.Subscription, error) {
+	if err := validatePeriod(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	if err := validateTrial(req.StartDate, req.EndDate, req.TrialEnd); err != nil {
+		return nil, err
+	}
+
+	billingPeriod := req.BillingPeriod
+	if billingPeriod == "" {
+		billingPeriod = models.BillingMonthly
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+
 	sub := &models.Subscription{
-		ServiceName: req.ServiceName,
-		Price:       req.Price,
-		UserID:      req.UserID,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
+		ServiceName:   req.ServiceName,
+		Category:      optionalCategory(req.Category),
+		Price:         req.Price,
+		Currency:      currency,
+		BillingPeriod: billingPeriod,
+		UserID:        req.UserID,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		TrialEnd:      req.TrialEnd,
+		Status:        models.StatusActive,
+		Version:       1,
+		Prices: []models.SubscriptionPrice{
+			{Price: req.Price, EffectiveFrom: req.StartDate},
+		},
 	}
-	return sub, s.repo.Create(sub)
+	if sub.TrialEnd != nil {
+		if sub.TrialEnd.Before(models.CurrentMonth()) {
+			// The trial is already over, so it converted when billing started.
+			convertedAt := sub.BillingStart().Time()
+			sub.TrialConvertedAt = &convertedAt
+		} else {
+			sub.Status = models.StatusTrial
+		}
+	}
+	return sub, nil
 }
 
-func (s *subscriptionService) Get(id int) (*models.Subscription, error) {
+func (s *subscriptionService) Get(id uuid.UUID) (*models.Subscription, error) {
 	sub, err := s.repo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -53,41 +166,42 @@ func (s *subscriptionService) Get(id int) (*models.Subscription, error) {
 		}
 		return nil, err
 	}
+	sub.Price = sub.PriceIn(models.CurrentMonth())
 	return sub, nil
 }
 
-const (
-	defaultLimit = 20
-	maxLimit     = 100
-)
-
-func (s *subscriptionService) List(userID, serviceName string, limit, offset int) ([]models.Subscription, *dtos.PaginationMeta, error) {
-
-	if limit <= 0 {
-		limit = defaultLimit
-	}
-	if limit > maxLimit {
-		limit = maxLimit
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	subs, total, err := s.repo.List(userID, serviceName, limit, offset)
+func (s *subscriptionService) List(filter dtos.SubscriptionFilter, page dtos.PageRequest) ([]dtos.SubscriptionListItem, *pagination.Meta, error) {
+	subs, meta, err := s.listPage(filter, page)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	meta := &dtos.PaginationMeta{
-		Limit:  limit,
-		Offset: offset,
-		Total:  int(total),
+	month := models.CurrentMonth()
+	items := make([]dtos.SubscriptionListItem, len(subs))
+	for i, sub := range subs {
+		sub.Price = sub.PriceIn(month)
+		items[i] = dtos.SubscriptionListItem{Subscription: sub}
+		if sub.Status != models.StatusPaused && sub.Status != models.StatusTrial {
+			items[i].MonthlyCost = sub.BillingPeriod.MonthlyEquivalent(sub.Price)
+		}
 	}
 
-	return subs, meta, nil
+	return items, meta, nil
 }
 
-func (s *subscriptionService) Update(id int, req dtos.UpdateSubscriptionRequest) (*models.Subscription, error) {
+// Export calls fn for every subscription matching filter, unpaginated and in
+// listing order, with the same prices and monthly costs as List.
+func (s *subscriptionService) Export(filter dtos.SubscriptionFilter, fn func(item dtos.SubscriptionListItem) error) error {
+	return s.repo.Stream(filter, models.CurrentMonth(), func(sub models.Subscription) error {
+		item := dtos.SubscriptionListItem{Subscription: sub}
+		if sub.Status != models.StatusPaused && sub.Status != models.StatusTrial {
+			item.MonthlyCost = sub.BillingPeriod.MonthlyEquivalent(sub.Price)
+		}
+		return fn(item)
+	})
+}
+
+func (s *subscriptionService) Update(id uuid.UUID, req dtos.UpdateSubscriptionRequest, ifMatch []int64) (*models.Subscription, error) {
 
 	sub, err := s.repo.GetByID(id)
 	if err != nil {
@@ -96,12 +210,21 @@ func (s *subscriptionService) Update(id int, req dtos.UpdateSubscriptionRequest)
 		}
 		return nil, err
 	}
+	if err := checkVersion(sub, ifMatch); err != nil {
+		return nil, err
+	}
 
 	if req.ServiceName != nil {
 		sub.ServiceName = *req.ServiceName
 	}
-	if req.Price != nil {
-		sub.Price = *req.Price
+	if req.Category != nil {
+		sub.Category = optionalCategory(req.Category)
+	}
+	if req.Currency != nil {
+		sub.Currency = *req.Currency
+	}
+	if req.BillingPeriod != nil {
+		sub.BillingPeriod = *req.BillingPeriod
 	}
 	if req.StartDate != nil {
 		sub.StartDate = *req.StartDate
@@ -109,49 +232,153 @@ func (s *subscriptionService) Update(id int, req dtos.UpdateSubscriptionRequest)
 	if req.EndDate != nil {
 		sub.EndDate = req.EndDate
 	}
-	updated, err := s.repo.Update(sub)
+	if req.TrialEnd != nil {
+		if sub.Status != models.StatusTrial {
+			return nil, ErrInvalidPeriod.WithField("trial_end", "can only be changed during the trial")
+		}
+		sub.TrialEnd = req.TrialEnd
+	}
+	if err := validatePeriod(sub.StartDate, sub.EndDate); err != nil {
+		return nil, err
+	}
+	if err := validateTrial(sub.StartDate, sub.EndDate, sub.TrialEnd); err != nil {
+		return nil, err
+	}
+
+	var newPrice *models.SubscriptionPrice
+	if req.Price != nil {
+		if newPrice, err = schedulePrice(sub, *req.Price, req.PriceEffectiveFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.repo.Update(sub, newPrice)
 	if err != nil {
 		return nil, err
 	}
 
 	if !updated {
-		return nil, fmt.Errorf("subscription not found")
+		return nil, lostRace(ifMatch)
 	}
 
+	s.spendChanged(sub.UserID)
 	return sub, nil
 
 }
 
-var ErrSubscriptionNotFound = errors.New("subscription not found")
+var (
+	ErrSubscriptionNotFound = apperr.New(apperr.NotFound, "subscription-not-found", "subscription not found")
+	ErrPreconditionFailed   = apperr.New(apperr.PreconditionFailed, "precondition-failed", "subscription has been modified, fetch it again and retry")
+	// ErrConcurrentUpdate means a change lost a race with another one. Unlike
+	// ErrPreconditionFailed the client did not ask for a particular version.
+	ErrConcurrentUpdate = apperr.New(apperr.Conflict, "concurrent-update", "subscription was changed at the same time, retry the request")
+)
+
+// Delete moves a subscription to the trash.
+func (s *subscriptionService) Delete(id uuid.UUID, ifMatch []int64) error {
+	_, err := s.delete(id, ifMatch)
+	return err
+}
 
-func (s *subscriptionService) Delete(id int) error {
-	found, err := s.repo.Delete(id)
+// delete moves a subscription to the trash and returns it as it was.
+func (s *subscriptionService) delete(id uuid.UUID, ifMatch []int64) (*models.Subscription, error) {
+	sub, err := s.repo.GetByID(id)
 	if err != nil {
-		return err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	if err := checkVersion(sub, ifMatch); err != nil {
+		return nil, err
+	}
+
+	found, err := s.repo.Delete(id, sub.Version)
+	if err != nil {
+		return nil, err
 	}
 	if !found {
-		return ErrSubscriptionNotFound
+		return nil, lostRace(ifMatch)
+	}
+	s.spendChanged(sub.UserID)
+	return sub, nil
+}
+
+// optionalCategory trims a category, mapping a missing or blank one to nil.
+func optionalCategory(category *string) *string {
+	if category == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*category)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// checkVersion compares sub with the versions a client expects it to have.
+// A nil ifMatch expects nothing.
+func checkVersion(sub *models.Subscription, ifMatch []int64) error {
+	if ifMatch == nil || slices.Contains(ifMatch, sub.Version) {
+		return nil
+	}
+	return ErrPreconditionFailed
+}
+
+// lostRace is the error for a versioned write that found the version it read
+// already replaced: the client's precondition failed when it sent If-Match
+// with a version, otherwise the change just collided with another one.
+func lostRace(ifMatch []int64) error {
+	if len(ifMatch) > 0 {
+		return ErrPreconditionFailed
+	}
+	return ErrConcurrentUpdate
+}
+
+var (
+	ErrInvalidPeriod           = apperr.New(apperr.Invalid, "invalid-period", "invalid period")
+	ErrExchangeRateUnavailable = apperr.New(apperr.Unprocessable, "exchange-rate-unavailable", "exchange rate unavailable")
+)
+
+func validatePeriod(startDate models.YearMonth, endDate *models.YearMonth) error {
+	if startDate.IsZero() {
+		return ErrInvalidPeriod.WithField("start_date", "is required")
+	}
+	if endDate != nil && endDate.Before(startDate) {
+		return ErrInvalidPeriod.WithField("end_date", "must not be before start_date")
 	}
 	return nil
 }
 
-func (s *subscriptionService) GetTotalCost(startDate, endDate, userID, serviceName string) (*dtos.TotalCostResponse, error) {
-	if startDate == "" || endDate == "" {
-		return nil, fmt.Errorf("start_date and end_date are required")
+func validateTrial(startDate models.YearMonth, endDate, trialEnd *models.YearMonth) error {
+	if trialEnd == nil {
+		return nil
+	}
+	if trialEnd.Before(startDate) {
+		return ErrInvalidPeriod.WithField("trial_end", "must not be before start_date")
 	}
+	if endDate != nil && trialEnd.After(*endDate) {
+		return ErrInvalidPeriod.WithField("trial_end", "must not be after end_date")
+	}
+	return nil
+}
 
-	result, err := s.repo.GetTotalCost(
-		startDate,
-		endDate,
-		userID,
-		serviceName,
-	)
+func (s *subscriptionService) GetTotalCost(startDate, endDate models.YearMonth, filter dtos.SubscriptionFilter, currency models.Currency, breakdown bool) (*dtos.TotalCostResponse, error) {
+	if startDate.IsZero() || endDate.IsZero() {
+		return nil, fmt.Errorf("%w: start_date and end_date are required", ErrInvalidPeriod)
+	}
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("%w: end_date must not be before start_date", ErrInvalidPeriod)
+	}
+
+	subs, err := s.repo.ListActiveInRange(startDate, endDate, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dtos.TotalCostResponse{
-		Total: int64(result.Total),
-		Count: int64(result.Count),
-	}, nil
+	if currency == "" {
+		currency = s.defaultCurrency
+	}
+
+	return calculateTotalCost(subs, startDate, endDate, newCurrencyConverter(s.rates, currency), breakdown)
 }
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/repository"
+)
+
+// racingRepo serves one subscription at version 3 and loses every
+// conditional write, as if another request changed it in between.
+type racingRepo struct {
+	repository.SubscriptionRepository
+	sub    models.Subscription
+	writes int
+}
+
+func (r *racingRepo) GetByID(id uuid.UUID) (*models.Subscription, error) {
+	sub := r.sub
+	return &sub, nil
+}
+
+func (r *racingRepo) Update(sub *models.Subscription, newPrice *models.SubscriptionPrice) (bool, error) {
+	r.writes++
+	return false, nil
+}
+
+func (r *racingRepo) Delete(id uuid.UUID, version int64) (bool, error) {
+	r.writes++
+	return false, nil
+}
+
+func TestVersionedWrites(t *testing.T) {
+	name := "Kion"
+	writes := map[string]func(svc SubscriptionService, id uuid.UUID, ifMatch []int64) error{
+		"update": func(svc SubscriptionService, id uuid.UUID, ifMatch []int64) error {
+			_, err := svc.Update(id, dtos.UpdateSubscriptionRequest{ServiceName: &name}, ifMatch)
+			return err
+		},
+		"patch": func(svc SubscriptionService, id uuid.UUID, ifMatch []int64) error {
+			same := func(doc []byte) ([]byte, error) { return doc, nil }
+			valid := func(any) error { return nil }
+			_, err := svc.Patch(id, same, ifMatch, valid)
+			return err
+		},
+		"delete": func(svc SubscriptionService, id uuid.UUID, ifMatch []int64) error {
+			return svc.Delete(id, ifMatch)
+		},
+	}
+
+	tests := []struct {
+		name       string
+		ifMatch    []int64
+		wantErr    error
+		wantWrites int
+	}{
+		{name: "without If-Match a lost race is a conflict", ifMatch: nil, wantErr: ErrConcurrentUpdate, wantWrites: 1},
+		{name: "with the read version a lost race fails the precondition", ifMatch: []int64{3}, wantErr: ErrPreconditionFailed, wantWrites: 1},
+		{name: "an outdated version fails before writing", ifMatch: []int64{2}, wantErr: ErrPreconditionFailed, wantWrites: 0},
+		{name: "only unknown tags fail before writing", ifMatch: []int64{}, wantErr: ErrPreconditionFailed, wantWrites: 0},
+	}
+
+	for op, write := range writes {
+		for _, tt := range tests {
+			t.Run(op+": "+tt.name, func(t *testing.T) {
+				repo := &racingRepo{sub: models.Subscription{
+					ID: uuid.New(), ServiceName: "Netflix", Price: 1000, Currency: "RUB",
+					BillingPeriod: models.BillingMonthly, Status: models.StatusActive,
+					StartDate: models.NewYearMonth(2025, time.July), Version: 3,
+				}}
+				svc := NewSubscriptionService(repo, nil, "RUB", 0, "off")
+
+				err := write(svc, repo.sub.ID, tt.ifMatch)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				if repo.writes != tt.wantWrites {
+					t.Errorf("writes = %d, want %d", repo.writes, tt.wantWrites)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+	"github.com/Ilmyrat1822/subs/internal/pagination"
+)
+
+// Trash lists the deleted subscriptions that have not been purged yet.
+func (s *subscriptionService) Trash(userID string, limit, offset int) ([]dtos.TrashItem, *pagination.Meta, error) {
+	limit, offset = pagination.Normalize(limit, offset)
+
+	subs, total, err := s.repo.ListDeleted(userID, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]dtos.TrashItem, len(subs))
+	for i, sub := range subs {
+		items[i] = dtos.TrashItem{
+			Subscription: sub,
+			PurgeAt:      sub.DeletedAt.Time.Add(s.trashRetention),
+		}
+	}
+
+	return items, pagination.OffsetMeta(limit, offset, total), nil
+}
+
+// Restore moves a subscription out of the trash, provided it still has one
+// of the versions in ifMatch. Like a new subscription, it goes through the
+// duplicate guard; in warn mode the likely duplicates are returned.
+func (s *subscriptionService) Restore(id uuid.UUID, ifMatch []int64) (*models.Subscription, []models.Subscription, error) {
+	sub, err := s.repo.GetDeleted(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("%w in trash", ErrSubscriptionNotFound)
+		}
+		return nil, nil, err
+	}
+	if err := checkVersion(sub, ifMatch); err != nil {
+		return nil, nil, err
+	}
+	duplicates, _, err := s.guardDuplicates(sub, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found, err := s.repo.Restore(id, sub.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, lostRace(ifMatch)
+	}
+
+	restored, err := s.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.spendChanged(restored.UserID)
+	return restored, duplicates, nil
+}
+
+// PurgeTrash permanently removes the subscriptions that have been in the
+// trash for longer than the retention period and returns how many there were.
+func (s *subscriptionService) PurgeTrash() (int64, error) {
+	purged, err := s.repo.Purge(time.Now().UTC().Add(-s.trashRetention))
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		log.Printf("Purged %d deleted subscriptions", purged)
+	}
+	return purged, nil
+}
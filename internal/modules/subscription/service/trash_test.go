@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+func (r *memorySubs) GetDeleted(id uuid.UUID) (*models.Subscription, error) {
+	i := r.find(id)
+	if i < 0 || !r.subs[i].DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	sub := r.subs[i]
+	return &sub, nil
+}
+
+func (r *memorySubs) Restore(id uuid.UUID, version int64) (bool, error) {
+	i := r.find(id)
+	if i < 0 || !r.subs[i].DeletedAt.Valid || r.subs[i].Version != version {
+		return false, nil
+	}
+	r.subs[i].DeletedAt = gorm.DeletedAt{}
+	r.subs[i].Version++
+	r.writes = append(r.writes, "restore "+r.subs[i].ServiceName)
+	return true, nil
+}
+
+func (r *memorySubs) Purge(deletedBefore time.Time) (int64, error) {
+	var purged int64
+	kept := r.subs[:0]
+	for _, sub := range r.subs {
+		if sub.DeletedAt.Valid && sub.DeletedAt.Time.Before(deletedBefore) {
+			purged++
+			continue
+		}
+		kept = append(kept, sub)
+	}
+	r.subs = kept
+	return purged, nil
+}
+
+// trashedSub is storedSub moved to the trash deletedAgo ago.
+func trashedSub(serviceName string, deletedAgo time.Duration) models.Subscription {
+	sub := storedSub(serviceName)
+	sub.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC().Add(-deletedAgo), Valid: true}
+	return sub
+}
+
+func TestRestore(t *testing.T) {
+	trashed := trashedSub("Netflix", time.Hour)
+	active := storedSub("Kion")
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		ifMatch []int64
+		wantErr error
+	}{
+		{name: "without If-Match", id: trashed.ID},
+		{name: "current version", id: trashed.ID, ifMatch: []int64{1}},
+		{name: "stale version", id: trashed.ID, ifMatch: []int64{2}, wantErr: ErrPreconditionFailed},
+		{name: "not deleted", id: active.ID, wantErr: ErrSubscriptionNotFound},
+		{name: "unknown", id: uuid.New(), wantErr: ErrSubscriptionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memorySubs{subs: []models.Subscription{trashed, active}}
+			svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardOff)
+
+			sub, _, err := svc.Restore(tt.id, tt.ifMatch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Restore() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.writes) != 0 {
+					t.Errorf("writes = %q, want none", repo.writes)
+				}
+				return
+			}
+			if sub.ID != trashed.ID || sub.DeletedAt.Valid || sub.Version != 2 {
+				t.Errorf("restored = %s deleted %v version %d, want %s not deleted version 2", sub.ID, sub.DeletedAt.Valid, sub.Version, trashed.ID)
+			}
+		})
+	}
+}
+
+// racingRestores loses every restore to a concurrent writer.
+type racingRestores struct {
+	*memorySubs
+}
+
+func (r racingRestores) Restore(id uuid.UUID, version int64) (bool, error) {
+	return false, nil
+}
+
+func TestRestoreLosingRace(t *testing.T) {
+	trashed := trashedSub("Netflix", time.Hour)
+	svc := NewSubscriptionService(racingRestores{&memorySubs{subs: []models.Subscription{trashed}}}, nil, "RUB", 0, dtos.DuplicateGuardOff)
+
+	if _, _, err := svc.Restore(trashed.ID, nil); !errors.Is(err, ErrConcurrentUpdate) {
+		t.Errorf("Restore() without If-Match error = %v, want %v", err, ErrConcurrentUpdate)
+	}
+	if _, _, err := svc.Restore(trashed.ID, []int64{1}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Restore() with If-Match error = %v, want %v", err, ErrPreconditionFailed)
+	}
+}
+
+func TestRestoreGuardsDuplicates(t *testing.T) {
+	// Netflix was subscribed to again after the old subscription was
+	// deleted; restoring the old one would count it twice.
+	trashed := trashedSub("Netflix", time.Hour)
+	replacement := storedSub("netflix")
+
+	t.Run("reject", func(t *testing.T) {
+		repo := &memorySubs{subs: []models.Subscription{trashed, replacement}}
+		svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardReject)
+
+		_, _, err := svc.Restore(trashed.ID, nil)
+		var dup *DuplicateError
+		if !errors.As(err, &dup) || len(dup.Existing) != 1 || dup.Existing[0].ID != replacement.ID {
+			t.Fatalf("Restore() error = %v, want a duplicate of %s", err, replacement.ID)
+		}
+		if len(repo.writes) != 0 {
+			t.Errorf("writes = %q, want none", repo.writes)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		repo := &memorySubs{subs: []models.Subscription{trashed, replacement}}
+		svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardWarn)
+
+		sub, duplicates, err := svc.Restore(trashed.ID, nil)
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if sub.DeletedAt.Valid || len(duplicates) != 1 || duplicates[0].ID != replacement.ID {
+			t.Errorf("Restore() = deleted %v with duplicates %v, want restored with %s", sub.DeletedAt.Valid, duplicates, replacement.ID)
+		}
+	})
+
+	t.Run("no overlap", func(t *testing.T) {
+		ended := storedSub("Netflix")
+		end := models.NewYearMonth(2025, time.January)
+		ended.StartDate, ended.EndDate = models.NewYearMonth(2024, time.January), &end
+		repo := &memorySubs{subs: []models.Subscription{trashed, ended}}
+		svc := NewSubscriptionService(repo, nil, "RUB", 0, dtos.DuplicateGuardReject)
+
+		if _, _, err := svc.Restore(trashed.ID, nil); err != nil {
+			t.Errorf("Restore() error = %v", err)
+		}
+	})
+}
+
+// purgeCutoffs records the cutoff of every purge.
+type purgeCutoffs struct {
+	*memorySubs
+	cutoffs []time.Time
+}
+
+func (r *purgeCutoffs) Purge(deletedBefore time.Time) (int64, error) {
+	r.cutoffs = append(r.cutoffs, deletedBefore)
+	return r.memorySubs.Purge(deletedBefore)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	// The database compares the cutoff with deleted_at by wall clock, so it
+	// must be in UTC like the stored times; a local time east of UTC would
+	// purge subscriptions hours early.
+	local := time.Local
+	time.Local = time.FixedZone("MSK", 3*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	const retention = 30 * 24 * time.Hour
+	expired := trashedSub("Netflix", retention+time.Minute)
+	kept := trashedSub("Kion", retention-time.Minute)
+	active := storedSub("Okko")
+	repo := &purgeCutoffs{memorySubs: &memorySubs{subs: []models.Subscription{expired, kept, active}}}
+	svc := NewSubscriptionService(repo, nil, "RUB", retention, dtos.DuplicateGuardOff)
+
+	before := time.Now().UTC().Add(-retention)
+	purged, err := svc.PurgeTrash()
+	after := time.Now().UTC().Add(-retention)
+	if err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+
+	if purged != 1 || len(repo.subs) != 2 || repo.find(expired.ID) >= 0 {
+		t.Errorf("purged %d, left %d subscriptions, want only %s purged", purged, len(repo.subs), expired.ID)
+	}
+	if len(repo.cutoffs) != 1 {
+		t.Fatalf("%d purges, want 1", len(repo.cutoffs))
+	}
+	if cutoff := repo.cutoffs[0]; cutoff.Location() != time.UTC || cutoff.Before(before) || cutoff.After(after) {
+		t.Errorf("cutoff = %s, want %s to %s in UTC", cutoff, before, after)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+	"github.com/Ilmyrat1822/subs/internal/modules/subscription/dtos"
+)
+
+// TrialsEnding lists the trials that convert to paid within the given
+// duration from now, soonest first.
+func (s *subscriptionService) TrialsEnding(within time.Duration, userID string) ([]dtos.TrialEndingItem, error) {
+	if within < 0 {
+		return nil, fmt.Errorf("%w: within must not be negative", ErrInvalidPeriod)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// A trial converts on the first day of the month after trial_end, so it
+	// converts by the deadline when trial_end is before the deadline's month.
+	lastTrialMonth := models.YearMonthOf(today.Add(within)).AddMonths(-1)
+
+	subs, err := s.repo.ListTrialsEndingBy(lastTrialMonth, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	month := models.CurrentMonth()
+	items := make([]dtos.TrialEndingItem, len(subs))
+	for i, sub := range subs {
+		sub.Price = sub.PriceIn(month)
+		convertsOn := sub.BillingStart().Time()
+		items[i] = dtos.TrialEndingItem{
+			Subscription: sub,
+			ConvertsOn:   convertsOn.Format(time.DateOnly),
+			DaysLeft:     max(0, int(convertsOn.Sub(today).Hours()/24)),
+		}
+	}
+	return items, nil
+}
+
+// ConvertEndedTrials moves every trial whose last month has passed to active
+// and records the conversion in its history. A trial that fails to convert
+// is logged and skipped, so it does not hold up the others; the failures are
+// returned joined, together with how many were converted.
+func (s *subscriptionService) ConvertEndedTrials() (int, error) {
+	subs, err := s.repo.ListTrialsEndingBy(models.CurrentMonth().AddMonths(-1), "")
+	if err != nil {
+		return 0, err
+	}
+
+	converted := 0
+	var errs []error
+	for _, sub := range subs {
+		effective := sub.BillingStart()
+		req := dtos.StatusChangeRequest{Effective: &effective, Reason: "trial ended"}
+		if _, err := s.transition(sub.ID, models.ActionConvert, req); err != nil {
+			err = fmt.Errorf("failed to convert trial of subscription %s: %w", sub.ID, err)
+			log.Print(err)
+			errs = append(errs, err)
+			continue
+		}
+		converted++
+	}
+	if converted > 0 {
+		log.Printf("Converted %d ended trials", converted)
+	}
+	return converted, errors.Join(errs...)
+}
@@ -0,0 +1,39 @@
+// Package pagination holds what the paginated listings of every module
+// share: the page size limits and the page description returned with a page.
+package pagination
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Normalize applies the default and maximum page size.
+func Normalize(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// Meta describes a page. Offset is only set in offset mode, Total only when
+// it was counted, and Next and Prev only when there are rows in that
+// direction.
+type Meta struct {
+	Limit  int    `json:"limit"`
+	Offset *int   `json:"offset,omitempty"`
+	Total  *int   `json:"total,omitempty"`
+	Next   string `json:"next,omitempty"`
+	Prev   string `json:"prev,omitempty"`
+} // @name PaginationMeta
+
+// OffsetMeta describes a page of offset pagination with its counted total.
+func OffsetMeta(limit, offset int, total int64) *Meta {
+	count := int(total)
+	return &Meta{Limit: limit, Offset: &offset, Total: &count}
+}
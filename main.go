@@ -4,13 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/Ilmyrat1822/subs/cmd"
-	_ "github.com/Ilmyrat1822/subs/docs"
+	"github.com/Ilmyrat1822/subs/docs"
 	internal "github.com/Ilmyrat1822/subs/internal/modules"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
@@ -21,12 +22,27 @@ import (
 // @host localhost:7777
 // @BasePath /
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := cmd.RunMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	server := cmd.NewServer()
-	server.Echo.GET("/swagger/*any", echoSwagger.EchoWrapHandler())
+	// Each API version has its own document; /swagger/ shows v1 as before.
+	docs.SwaggerInfov2.Version = "2.0"
+	docs.SwaggerInfov2.Description = "Subscription management service. v2 covers a subset of v1: " +
+		"listing, creating, reading, updating and deleting subscriptions, the total and the pause, " +
+		"resume, cancel and reactivate actions. Everything else, including budgets, is served by v1 only."
+	server.Echo.GET("/swagger/v1/*any", echoSwagger.EchoWrapHandler(echoSwagger.InstanceName("v1")))
+	server.Echo.GET("/swagger/v2/*any", echoSwagger.EchoWrapHandler(echoSwagger.InstanceName("v2")))
+	server.Echo.GET("/swagger/*any", echoSwagger.EchoWrapHandler(echoSwagger.InstanceName("v1")))
 	internal.InitRouters(server)
 
 	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
+	server.Jobs.Start(signalCtx)
 	// Start server
 	go func() {
 		if err := server.Echo.Start(fmt.Sprintf("0.0.0.0:%v", server.Config.Port)); err != nil && !errors.Is(
@@ -1,3 +1,2 @@
-DROP INDEX IF EXISTS idx_subscriptions_user_id;
 DROP INDEX IF EXISTS idx_subscriptions_user_service;
 DROP INDEX IF EXISTS idx_subscriptions_start_date;
@@ -1,7 +1,3 @@
--- Index for filtering by user
-CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id
-ON subscriptions (user_id);
-
 -- Composite index for user + service
 CREATE INDEX IF NOT EXISTS idx_subscriptions_user_service
 ON subscriptions (user_id, service_name);
@@ -0,0 +1,34 @@
+-- Convert MM-YYYY strings to DATE columns holding the first day of the month.
+-- Rows that cannot be parsed are reported and the migration is aborted, so
+-- nothing is converted until they are fixed by hand.
+DO $$
+DECLARE
+    invalid_rows TEXT;
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM information_schema.columns
+        WHERE table_name = 'subscriptions'
+          AND column_name = 'start_date'
+          AND data_type = 'character varying'
+    ) THEN
+        RETURN;
+    END IF;
+
+    SELECT string_agg(
+        format('id=%s start_date=%L end_date=%L', id, start_date, end_date),
+        '; ' ORDER BY id
+    )
+    INTO invalid_rows
+    FROM subscriptions
+    WHERE trim(start_date) !~ '^(0?[1-9]|1[0-2])-[0-9]{4}$'
+       OR (end_date IS NOT NULL AND trim(end_date) !~ '^(0?[1-9]|1[0-2])-[0-9]{4}$');
+
+    IF invalid_rows IS NOT NULL THEN
+        RAISE EXCEPTION 'cannot convert subscription dates, expected MM-YYYY in: %', invalid_rows;
+    END IF;
+
+    ALTER TABLE subscriptions
+        ALTER COLUMN start_date TYPE DATE USING to_date(trim(start_date), 'MM-YYYY'),
+        ALTER COLUMN end_date TYPE DATE USING to_date(trim(end_date), 'MM-YYYY');
+END $$;
@@ -0,0 +1,20 @@
+-- Integer ids are restored from subscription_legacy_ids where possible;
+-- rows created after the upgrade get fresh values from the sequence.
+ALTER TABLE subscriptions ADD COLUMN old_id INTEGER;
+
+UPDATE subscriptions s
+SET old_id = l.legacy_id
+FROM subscription_legacy_ids l
+WHERE l.id = s.id;
+
+CREATE SEQUENCE IF NOT EXISTS subscriptions_id_seq OWNED BY subscriptions.old_id;
+SELECT setval('subscriptions_id_seq', COALESCE((SELECT MAX(old_id) FROM subscriptions), 0) + 1, false);
+UPDATE subscriptions SET old_id = nextval('subscriptions_id_seq') WHERE old_id IS NULL;
+
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_pkey;
+ALTER TABLE subscriptions DROP COLUMN id;
+ALTER TABLE subscriptions RENAME COLUMN old_id TO id;
+ALTER TABLE subscriptions ALTER COLUMN id SET DEFAULT nextval('subscriptions_id_seq');
+ALTER TABLE subscriptions ADD PRIMARY KEY (id);
+
+DROP TABLE IF EXISTS subscription_legacy_ids;
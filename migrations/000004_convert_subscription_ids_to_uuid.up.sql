@@ -0,0 +1,31 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Replace the SERIAL primary key created by 000001 with a UUID. The old
+-- integer ids are kept in subscription_legacy_ids so callers holding them
+-- can look up the new identifiers.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1
+        FROM information_schema.columns
+        WHERE table_name = 'subscriptions'
+          AND column_name = 'id'
+          AND data_type = 'integer'
+    ) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE subscriptions ADD COLUMN new_id UUID NOT NULL DEFAULT uuid_generate_v4();
+
+    CREATE TABLE subscription_legacy_ids (
+        legacy_id INTEGER PRIMARY KEY,
+        id UUID NOT NULL UNIQUE
+    );
+    INSERT INTO subscription_legacy_ids (legacy_id, id)
+    SELECT id, new_id FROM subscriptions;
+
+    ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_pkey;
+    ALTER TABLE subscriptions DROP COLUMN id;
+    ALTER TABLE subscriptions RENAME COLUMN new_id TO id;
+    ALTER TABLE subscriptions ADD PRIMARY KEY (id);
+END $$;
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS chk_subscriptions_billing_period;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_period;
@@ -0,0 +1,8 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS billing_period VARCHAR(32) NOT NULL DEFAULT 'monthly';
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT chk_subscriptions_billing_period CHECK (
+        billing_period IN ('weekly', 'monthly', 'quarterly', 'yearly')
+        OR billing_period ~ '^every-[1-9][0-9]*-months$'
+    );
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS exchange_rates;
+
+UPDATE subscriptions SET price = price / 100;
+ALTER TABLE subscriptions ALTER COLUMN price TYPE INTEGER;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
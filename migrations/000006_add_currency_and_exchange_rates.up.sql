@@ -0,0 +1,19 @@
+-- Prices become amounts in minor units of an ISO 4217 currency. Existing
+-- prices were whole roubles.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE subscriptions ALTER COLUMN price TYPE BIGINT;
+UPDATE subscriptions SET price = price * 100;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rate_date DATE NOT NULL,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (base_currency <> quote_currency)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exchange_rates_date_pair
+ON exchange_rates (rate_date, base_currency, quote_currency);
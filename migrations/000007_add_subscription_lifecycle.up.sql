@@ -0,0 +1,37 @@
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT chk_subscriptions_status
+    CHECK (status IN ('trial', 'active', 'paused', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_status ON subscriptions (status);
+
+CREATE TABLE IF NOT EXISTS subscription_status_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    action VARCHAR(16) NOT NULL,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    effective_month DATE NOT NULL,
+    previous_end_date DATE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_status_changes_subscription
+ON subscription_status_changes (subscription_id, created_at);
+
+-- Months from paused_from up to, but not including, resumed_from are not charged.
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    paused_from DATE NOT NULL,
+    resumed_from DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (resumed_from IS NULL OR resumed_from >= paused_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_pauses_subscription
+ON subscription_pauses (subscription_id);
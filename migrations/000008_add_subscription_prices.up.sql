@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    effective_from DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_prices_effective
+ON subscription_prices (subscription_id, effective_from);
+
+-- Every existing subscription starts its history with its current price.
+INSERT INTO subscription_prices (subscription_id, price, effective_from)
+SELECT id, price, start_date
+FROM subscriptions
+ON CONFLICT (subscription_id, effective_from) DO NOTHING;
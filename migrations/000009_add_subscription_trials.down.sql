@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_trial_end;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS chk_subscriptions_trial_end;
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS trial_converted_at,
+    DROP COLUMN IF EXISTS trial_end;
@@ -0,0 +1,11 @@
+-- trial_end is the last month of the free trial; billing starts the month after.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS trial_end DATE,
+    ADD COLUMN IF NOT EXISTS trial_converted_at TIMESTAMP;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT chk_subscriptions_trial_end
+    CHECK (trial_end IS NULL OR trial_end >= start_date);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_trial_end
+ON subscriptions (status, trial_end);
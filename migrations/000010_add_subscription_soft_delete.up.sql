@@ -0,0 +1,4 @@
+-- Deleted subscriptions stay in the table until the purge job removes them.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_deleted_at ON subscriptions (deleted_at);
@@ -0,0 +1,2 @@
+-- version is bumped on every write and exposed as the ETag.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS budget_events;
+DROP TABLE IF EXISTS budgets;
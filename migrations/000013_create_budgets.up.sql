@@ -0,0 +1,34 @@
+-- Spending limits of a user per month or year, optionally for one service.
+CREATE TABLE IF NOT EXISTS budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    period VARCHAR(16) NOT NULL,
+    limit_amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    service_name VARCHAR(255),
+    thresholds INTEGER[] NOT NULL DEFAULT '{80,100}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_budgets_period CHECK (period IN ('monthly', 'yearly')),
+    CONSTRAINT chk_budgets_limit CHECK (limit_amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets (user_id);
+
+-- A threshold of a budget reached in one period. notified_at stays NULL until
+-- a notification has been sent for it.
+CREATE TABLE IF NOT EXISTS budget_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    budget_id UUID NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    threshold INTEGER NOT NULL,
+    spend BIGINT NOT NULL,
+    limit_amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP,
+    CONSTRAINT uq_budget_events_threshold UNIQUE (budget_id, period_start, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_events_pending
+ON budget_events (created_at) WHERE notified_at IS NULL;
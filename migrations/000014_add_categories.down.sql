@@ -0,0 +1,3 @@
+ALTER TABLE budgets DROP COLUMN IF EXISTS category;
+DROP INDEX IF EXISTS idx_subscriptions_user_category;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS category;
@@ -0,0 +1,10 @@
+-- A free-form category such as "streaming" or "music", which budgets can be
+-- scoped to. Categories are compared case-insensitively.
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS category VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_category
+ON subscriptions (user_id, lower(category));
+
+ALTER TABLE budgets
+    ADD COLUMN IF NOT EXISTS category VARCHAR(64);
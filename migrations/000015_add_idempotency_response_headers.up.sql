@@ -0,0 +1,3 @@
+-- Headers of the stored response that are replayed along with its body.
+ALTER TABLE idempotency_keys
+    ADD COLUMN IF NOT EXISTS response_headers JSONB;
@@ -0,0 +1,4 @@
+-- Service names are matched case-insensitively, like categories, so exact
+-- lookups such as a budget's spend go through lower(service_name).
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_service_lower
+ON subscriptions (user_id, lower(service_name));
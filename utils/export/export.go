@@ -0,0 +1,218 @@
+// Package export writes tables as CSV, NDJSON or XLSX, one row at a time, so
+// that listings of any size can be streamed to the client.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+)
+
+// Format is an output format of a listing.
+type Format string
+
+const (
+	JSON   Format = "json"
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+	XLSX   Format = "xlsx"
+)
+
+var contentTypes = map[Format]string{
+	JSON:   "application/json",
+	CSV:    "text/csv",
+	NDJSON: "application/x-ndjson",
+	XLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ErrNotAcceptable means neither the format parameter nor the Accept header
+// names a supported format.
+var ErrNotAcceptable = apperr.New(
+	apperr.NotAcceptable,
+	"not-acceptable",
+	"no acceptable format, expected json, csv, ndjson or xlsx",
+)
+
+// ContentType is the media type of f, with a charset for text formats.
+func (f Format) ContentType() string {
+	if f == CSV || f == NDJSON {
+		return contentTypes[f] + "; charset=utf-8"
+	}
+	return contentTypes[f]
+}
+
+// Negotiate picks the output format. An explicit format parameter wins;
+// otherwise the most preferred supported type of the Accept header is used,
+// and JSON when the header is empty or accepts anything.
+func Negotiate(format, accept string) (Format, error) {
+	if format != "" {
+		f := Format(strings.ToLower(format))
+		if _, ok := contentTypes[f]; !ok {
+			return "", ErrNotAcceptable
+		}
+		return f, nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return JSON, nil
+	}
+
+	type candidate struct {
+		format Format
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "application/*":
+			candidates = append(candidates, candidate{JSON, q})
+		case "text/*":
+			candidates = append(candidates, candidate{CSV, q})
+		default:
+			for f, contentType := range contentTypes {
+				if mediaType == contentType {
+					candidates = append(candidates, candidate{f, q})
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrNotAcceptable
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format, nil
+}
+
+// Writer writes a table. Values are strings, integers, floats, booleans or
+// nil for an empty cell.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []any) error
+	// Close finishes the output; the table is incomplete until it is called.
+	Close() error
+}
+
+// NewWriter returns a writer of f to w. JSON is not a table format.
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case XLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("export: %s is not a table format", f)
+}
+
+// ParseColumns parses a comma-separated column list against the keys of
+// allowed, returning defaults when value is empty.
+func ParseColumns[V any](value string, allowed map[string]V, defaults []string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return defaults, nil
+	}
+	var columns []string
+	seen := map[string]bool{}
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if _, ok := allowed[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) WriteHeader(columns []string) error {
+	return cw.w.Write(columns)
+}
+
+func (cw *csvWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = csvCell(value)
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// csvCell formats a value for CSV. Text that a spreadsheet would read as a
+// formula is prefixed with a quote, so pasted exports cannot run formulas.
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (nw *ndjsonWriter) WriteHeader(columns []string) error {
+	nw.columns = columns
+	return nil
+}
+
+// WriteRow writes the row as one JSON object with the columns in order.
+func (nw *ndjsonWriter) WriteRow(values []any) error {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(nw.columns[i])
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return nw.enc.Encode(json.RawMessage(b.String()))
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		accept  string
+		want    Format
+		wantErr error
+	}{
+		{name: "nothing asked", want: JSON},
+		{name: "format wins over accept", format: "CSV", accept: "application/json", want: CSV},
+		{name: "unknown format", format: "xml", wantErr: ErrNotAcceptable},
+		{name: "anything", accept: "*/*", want: JSON},
+		{name: "json", accept: "application/json", want: JSON},
+		{name: "csv with charset", accept: "text/csv; charset=utf-8", want: CSV},
+		{name: "any text", accept: "text/*", want: CSV},
+		{name: "ndjson", accept: "application/x-ndjson", want: NDJSON},
+		{name: "xlsx", accept: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", want: XLSX},
+		{name: "highest quality wins", accept: "text/csv;q=0.5, application/x-ndjson;q=0.9, */*;q=0.1", want: NDJSON},
+		{name: "first of equal quality", accept: "text/csv, application/x-ndjson", want: CSV},
+		{name: "refused type is skipped", accept: "text/csv;q=0, application/json;q=0.2", want: JSON},
+		{name: "malformed parts are skipped", accept: "text/csv;q=abc, ;;, application/x-ndjson", want: NDJSON},
+		{name: "unsupported", accept: "application/xml, image/png", wantErr: ErrNotAcceptable},
+		{name: "everything refused", accept: "*/*;q=0", wantErr: ErrNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Negotiate(tt.format, tt.accept)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Negotiate(%q, %q) = %q, %v, want %q, %v", tt.format, tt.accept, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeTable writes columns and rows in format f and returns the output.
+func writeTable(t *testing.T, f Format, columns []string, rows ...[]any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(f, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter(%s) error = %v", f, err)
+	}
+	if err := w.WriteHeader(columns); err != nil {
+		t.Fatalf("WriteHeader() error = %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow(%v) error = %v", row, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: "Netflix", want: "Netflix"},
+		{value: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{value: "+7 999", want: "'+7 999"},
+		{value: "-1+2", want: "'-1+2"},
+		{value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{value: "\tcmd", want: "'\tcmd"},
+		{value: "\rcmd", want: "'\rcmd"},
+		{value: "a=b", want: "a=b"},
+		{value: "", want: ""},
+		{value: nil, want: ""},
+		{value: int64(-1500), want: "-1500"},
+		{value: 33.5, want: "33.5"},
+		{value: true, want: "true"},
+	}
+
+	for _, tt := range tests {
+		if got := csvCell(tt.value); got != tt.want {
+			t.Errorf("csvCell(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	got := writeTable(t, CSV, []string{"service_name", "price", "end_date"},
+		[]any{"Netflix, Inc.", int64(39900), nil},
+		[]any{"=1+1", int64(-100), "12-2025"},
+	)
+	want := "service_name,price,end_date\n" +
+		"\"Netflix, Inc.\",39900,\n" +
+		"'=1+1,-100,12-2025\n"
+	if string(got) != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	got := writeTable(t, NDJSON, []string{"service_name", "price", "end_date", "trial"},
+		[]any{"=Netflix", int64(39900), nil, false},
+		[]any{"Kion \"HD\"", 12.5, "12-2025", true},
+	)
+	// Columns keep their order and nothing is escaped for spreadsheets.
+	want := `{"service_name":"=Netflix","price":39900,"end_date":null,"trial":false}` + "\n" +
+		`{"service_name":"Kion \"HD\"","price":12.5,"end_date":"12-2025","trial":true}` + "\n"
+	if string(got) != want {
+		t.Errorf("NDJSON =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNewWriterRejectsJSON(t *testing.T) {
+	if _, err := NewWriter(JSON, &bytes.Buffer{}); err == nil {
+		t.Error("NewWriter(json) succeeded, want an error")
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	allowed := map[string]bool{"service_name": true, "price": true, "status": true}
+	defaults := []string{"service_name", "price"}
+
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: defaults},
+		{value: "status, price", want: []string{"status", "price"}},
+		{value: "price,price,status", want: []string{"price", "status"}},
+		{value: "price,secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColumns(tt.value, allowed, defaults)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseColumns(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// sheetXML is the part of a worksheet the writer produces.
+type sheetXML struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXWriter(t *testing.T) {
+	out := writeTable(t, XLSX, []string{"service_name", "price", "end_date", "trial"},
+		[]any{"Netflix <HD> & more", int64(39900), nil, true},
+		[]any{"=1+1", 12.5, "12-2025", false},
+	)
+
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+	var names []string
+	var sheet []byte
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet, _ = io.ReadAll(r)
+		r.Close()
+	}
+	wantNames := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("parts = %q, want %q", names, wantNames)
+	}
+
+	var parsed sheetXML
+	if err := xml.Unmarshal(sheet, &parsed); err != nil {
+		t.Fatalf("worksheet is not valid XML: %v\n%s", err, sheet)
+	}
+
+	type cell struct{ ref, typ, value string }
+	var got [][]cell
+	for i, row := range parsed.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d numbered %d", i+1, row.R)
+		}
+		var cells []cell
+		for _, c := range row.Cells {
+			value := c.Value
+			if c.Type == "inlineStr" {
+				value = c.Inline
+			}
+			cells = append(cells, cell{c.Ref, c.Type, value})
+		}
+		got = append(got, cells)
+	}
+	want := [][]cell{
+		{{"A1", "inlineStr", "service_name"}, {"B1", "inlineStr", "price"}, {"C1", "inlineStr", "end_date"}, {"D1", "inlineStr", "trial"}},
+		// The empty end date has no cell; text stays text, so "=1+1" is no formula.
+		{{"A2", "inlineStr", "Netflix <HD> & more"}, {"B2", "", "39900"}, {"D2", "b", "1"}},
+		{{"A3", "inlineStr", "=1+1"}, {"B3", "", "12.5"}, {"C3", "inlineStr", "12-2025"}, {"D3", "b", "0"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cells = %v, want %v", got, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+// MaxServiceNameLength is the longest service name accepted, in characters.
+const MaxServiceNameLength = 100
+
+// serviceNamePattern allows letters, digits, spaces and the punctuation
+// found in product names, such as "Yandex Plus" or "Disney+ (Family)". The
+// name must start with a letter or digit.
+var serviceNamePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}\p{M} .,:!?&+'()/_-]*$`)
+
+func registerRules(v *validator.Validate) {
+	// Registration only fails for empty tags or nil functions.
+	must(v.RegisterValidation("mmyyyy", validateYearMonth))
+	must(v.RegisterValidation("monthgtefield", compareMonthField(func(c int) bool { return c >= 0 })))
+	must(v.RegisterValidation("monthltefield", compareMonthField(func(c int) bool { return c <= 0 })))
+	must(v.RegisterValidation("servicename", validateServiceName))
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// validateYearMonth implements mmyyyy: a string in MM-YYYY format, or a
+// models.YearMonth that names a real month.
+func validateYearMonth(fl validator.FieldLevel) bool {
+	month, ok := yearMonthOf(fl.Field())
+	return ok && !month.IsZero()
+}
+
+// compareMonthField implements monthgtefield and monthltefield, which compare
+// a month with the month in the field named by the parameter, such as
+// end_date with StartDate. They pass when either month is missing, which is
+// left to required.
+func compareMonthField(accept func(cmp int) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		month, ok := yearMonthOf(fl.Field())
+		if !ok || month.IsZero() {
+			return true
+		}
+		other, _, _, found := fl.GetStructFieldOK2()
+		if !found {
+			return true
+		}
+		otherMonth, ok := yearMonthOf(other)
+		if !ok || otherMonth.IsZero() {
+			return true
+		}
+		return accept(month.Compare(otherMonth))
+	}
+}
+
+// validateServiceName implements servicename: at most MaxServiceNameLength
+// characters of serviceNamePattern, without surrounding spaces.
+func validateServiceName(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	return name == strings.TrimSpace(name) &&
+		utf8.RuneCountInString(name) <= MaxServiceNameLength &&
+		serviceNamePattern.MatchString(name)
+}
+
+var yearMonthType = reflect.TypeOf(models.YearMonth{})
+
+// yearMonthOf reads a month from a string, a models.YearMonth, a type
+// defined on it, or a pointer to one; ok is false for anything else or a
+// malformed string.
+func yearMonthOf(field reflect.Value) (models.YearMonth, bool) {
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return models.YearMonth{}, true
+		}
+		field = field.Elem()
+	}
+	switch {
+	case field.Type().ConvertibleTo(yearMonthType):
+		return field.Convert(yearMonthType).Interface().(models.YearMonth), true
+	case field.Kind() == reflect.String:
+		if field.String() == "" {
+			return models.YearMonth{}, true
+		}
+		month, err := models.ParseYearMonth(field.String())
+		return month, err == nil
+	}
+	return models.YearMonth{}, false
+}
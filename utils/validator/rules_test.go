@@ -0,0 +1,133 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Ilmyrat1822/subs/internal/models"
+)
+
+func newRules() *validator.Validate {
+	v := validator.New()
+	registerRules(v)
+	return v
+}
+
+func TestYearMonthRule(t *testing.T) {
+	type text struct {
+		Month string `validate:"mmyyyy"`
+	}
+	type month struct {
+		Month models.YearMonth `validate:"mmyyyy"`
+	}
+	type optional struct {
+		Month *models.YearMonth `validate:"mmyyyy"`
+	}
+	type number struct {
+		Month int `validate:"mmyyyy"`
+	}
+	v := newRules()
+	july := models.NewYearMonth(2025, time.July)
+
+	tests := []struct {
+		name  string
+		value any
+		valid bool
+	}{
+		{name: "string", value: text{"07-2025"}, valid: true},
+		{name: "ISO string", value: text{"2025-07"}, valid: false},
+		{name: "bad month", value: text{"13-2025"}, valid: false},
+		{name: "empty string", value: text{""}, valid: false},
+		{name: "month", value: month{july}, valid: true},
+		{name: "zero month", value: month{}, valid: false},
+		{name: "pointer to month", value: optional{&july}, valid: true},
+		{name: "nil pointer", value: optional{}, valid: false},
+		{name: "number", value: number{72025}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Struct(tt.value)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("Struct(%+v) valid = %v, want %v (%v)", tt.value, valid, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestMonthFieldRules(t *testing.T) {
+	type window struct {
+		From string
+		To   string `validate:"monthgtefield=From"`
+	}
+	type deadline struct {
+		StartDate *models.YearMonth
+		TrialEnd  models.YearMonth `validate:"monthltefield=StartDate"`
+	}
+	v := newRules()
+	ymp := func(month time.Month) *models.YearMonth {
+		m := models.NewYearMonth(2025, month)
+		return &m
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		valid bool
+	}{
+		{name: "after", value: window{From: "07-2025", To: "08-2025"}, valid: true},
+		{name: "same month", value: window{From: "07-2025", To: "07-2025"}, valid: true},
+		{name: "before", value: window{From: "07-2025", To: "06-2025"}, valid: false},
+		{name: "across years", value: window{From: "12-2024", To: "01-2025"}, valid: true},
+		{name: "missing other", value: window{To: "06-2025"}, valid: true},
+		{name: "malformed other", value: window{From: "July", To: "06-2025"}, valid: true},
+		{name: "lte before", value: deadline{StartDate: ymp(time.August), TrialEnd: *ymp(time.July)}, valid: true},
+		{name: "lte after", value: deadline{StartDate: ymp(time.June), TrialEnd: *ymp(time.July)}, valid: false},
+		{name: "lte nil other", value: deadline{TrialEnd: *ymp(time.July)}, valid: true},
+		{name: "lte zero month", value: deadline{StartDate: ymp(time.June)}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Struct(tt.value)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("Struct(%+v) valid = %v, want %v (%v)", tt.value, valid, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestServiceNameRule(t *testing.T) {
+	v := newRules()
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "Yandex Plus", valid: true},
+		{name: "Disney+ (Family)", valid: true},
+		{name: "Кинопоиск", valid: true},
+		{name: "1Password", valid: true},
+		{name: "AT&T TV: Sports/News", valid: true},
+		{name: strings.Repeat("я", MaxServiceNameLength), valid: true},
+		{name: strings.Repeat("я", MaxServiceNameLength+1), valid: false},
+		{name: "", valid: false},
+		{name: " Netflix", valid: false},
+		{name: "Netflix ", valid: false},
+		{name: "+Plus", valid: false},
+		{name: "Netflix<script>", valid: false},
+		{name: "Spotify\nPremium", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Var(tt.name, "servicename")
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("servicename(%q) valid = %v, want %v (%v)", tt.name, valid, tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"strconv"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+)
+
+// summaryKey translates the text that introduces the field messages.
+const summaryKey = "validation_failed"
+
+// messages holds the texts of the custom rules and of summaryKey in every
+// supported language. {0} is the field and {1} the rule's parameter.
+var messages = map[string]map[string]string{
+	"en": {
+		summaryKey:      "validation failed",
+		"mmyyyy":        "{0} must be a month in MM-YYYY format",
+		"monthgtefield": "{0} must not be before {1}",
+		"monthltefield": "{0} must not be after {1}",
+		"servicename": "{0} must be at most " + strconv.Itoa(MaxServiceNameLength) +
+			" letters, digits, spaces or .,:!?&+'()/_- and start with a letter or digit",
+	},
+	"ru": {
+		summaryKey:      "ошибка валидации",
+		"mmyyyy":        "{0} должен быть месяцем в формате ММ-ГГГГ",
+		"monthgtefield": "{0} не может быть раньше {1}",
+		"monthltefield": "{0} не может быть позже {1}",
+		"servicename": "{0} должен содержать не более " + strconv.Itoa(MaxServiceNameLength) +
+			" букв, цифр, пробелов или символов .,:!?&+'()/_- и начинаться с буквы или цифры",
+	},
+}
+
+// newTranslator registers the built-in and custom messages of every
+// supported language with v. English is the fallback.
+func newTranslator(v *validator.Validate) *ut.UniversalTranslator {
+	uni := ut.New(en.New(), en.New(), ru.New())
+
+	enTrans, _ := uni.GetTranslator("en")
+	must(enTranslations.RegisterDefaultTranslations(v, enTrans))
+	ruTrans, _ := uni.GetTranslator("ru")
+	must(ruTranslations.RegisterDefaultTranslations(v, ruTrans))
+
+	for lang, texts := range messages {
+		trans, _ := uni.GetTranslator(lang)
+		must(trans.Add(summaryKey, texts[summaryKey], false))
+		for tag, text := range texts {
+			if tag == summaryKey {
+				continue
+			}
+			must(v.RegisterTranslation(tag, trans, registerText(tag, text), translateField))
+		}
+	}
+	return uni
+}
+
+func registerText(tag, text string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, text, true)
+	}
+}
+
+func translateField(trans ut.Translator, fe validator.FieldError) string {
+	msg, err := trans.T(fe.Tag(), fe.Field(), jsonName(fe.Param()))
+	if err != nil {
+		return fe.Error()
+	}
+	return msg
+}
@@ -1,21 +1,91 @@
 package validator
 
 import (
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
 )
 
 type CustomValidator struct {
-	validator *validator.Validate
+	validator  *validator.Validate
+	translator *ut.UniversalTranslator
 }
 
+// Validate checks i against its validate tags. Failures are reported as an
+// apperr validation error with one entry per field, named as in JSON, which
+// is rendered in the client's language when it reaches the error handler.
 func (cv *CustomValidator) Validate(i interface{}) error {
-	if err := cv.validator.Struct(i); err != nil {
-		return echo.NewHTTPError(400, err.Error())
+	err := cv.validator.Struct(i)
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	return cv.validationError(errs, nil).Localized(func(languages []string) *apperr.Error {
+		return cv.validationError(errs, languages)
+	})
+}
+
+// validationError describes errs in the first of languages that is
+// supported, English by default.
+func (cv *CustomValidator) validationError(errs validator.ValidationErrors, languages []string) *apperr.Error {
+	trans, _ := cv.translator.FindTranslator(languages...)
+	fields := make([]apperr.FieldError, len(errs))
+	for n, fe := range errs {
+		fields[n] = apperr.FieldError{Field: fieldPath(fe), Message: fe.Translate(trans)}
+	}
+	summary, err := trans.T(summaryKey)
+	if err != nil {
+		summary = "validation failed"
+	}
+	return apperr.Validation(summary, fields...)
+}
+
+// fieldPath drops the struct name from the namespace of fe, leaving the
+// JSON path of the field such as "operations[0].op".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// jsonName turns the Go name of a field referenced by a cross-field rule,
+// such as StartDate, into its JSON name start_date.
+func jsonName(field string) string {
+	var b strings.Builder
+	for i, r := range field {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
 	}
-	return nil
+	return b.String()
 }
 
 func NewValidator() *CustomValidator {
-	return &CustomValidator{validator: validator.New()}
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, key := range []string{"json", "query"} {
+			name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	registerRules(v)
+	return &CustomValidator{validator: v, translator: newTranslator(v)}
 }
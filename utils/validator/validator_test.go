@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Ilmyrat1822/subs/internal/apperr"
+)
+
+type operation struct {
+	Op string `json:"op" validate:"required,oneof=create delete"`
+}
+
+type request struct {
+	ServiceName string      `json:"service_name" validate:"required"`
+	Price       int         `json:"price" validate:"gte=0"`
+	Operations  []operation `json:"operations" validate:"dive"`
+}
+
+// problemOf renders the error of validating req as the server answers it.
+func problemOf(t *testing.T, req request, acceptLanguage string) apperr.Problem {
+	t.Helper()
+	err := NewValidator().Validate(req)
+	if err == nil {
+		t.Fatalf("Validate(%+v) succeeded", req)
+	}
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/v1/subs", nil)
+	httpReq.Header.Set("Accept-Language", acceptLanguage)
+	rec := httptest.NewRecorder()
+	apperr.HTTPErrorHandler(err, echo.New().NewContext(httpReq, rec))
+
+	if got := rec.Header().Get(echo.HeaderContentType); got != apperr.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", got, apperr.ProblemContentType)
+	}
+	var p apperr.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("body is not a problem: %v\n%s", err, rec.Body)
+	}
+	return p
+}
+
+func TestValidateProblem(t *testing.T) {
+	p := problemOf(t, request{Price: -1, Operations: []operation{{Op: "create"}, {Op: "merge"}}}, "")
+
+	if p.Status != http.StatusBadRequest || p.Type != "/problems/validation-failed" {
+		t.Errorf("problem = %d %s, want 400 /problems/validation-failed", p.Status, p.Type)
+	}
+	want := []apperr.FieldError{
+		{Field: "service_name", Message: "service_name is a required field"},
+		{Field: "price", Message: "price must be 0 or greater"},
+		{Field: "operations[1].op", Message: "op must be one of [create delete]"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", p.Errors, want)
+	}
+	wantDetail := "validation failed: service_name is a required field; price must be 0 or greater; op must be one of [create delete]"
+	if p.Detail != wantDetail {
+		t.Errorf("detail = %q, want %q", p.Detail, wantDetail)
+	}
+}
+
+func TestValidateProblemLocalized(t *testing.T) {
+	p := problemOf(t, request{}, "ru-RU, en;q=0.5")
+
+	if len(p.Errors) != 1 || p.Errors[0].Field != "service_name" {
+		t.Fatalf("errors = %+v, want one for service_name", p.Errors)
+	}
+	if want := "service_name обязательное поле"; p.Errors[0].Message != want {
+		t.Errorf("message = %q, want %q", p.Errors[0].Message, want)
+	}
+	if want := "ошибка валидации: service_name обязательное поле"; p.Detail != want {
+		t.Errorf("detail = %q, want %q", p.Detail, want)
+	}
+}